@@ -4,17 +4,19 @@ import (
 	"log"
 
 	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/pkg/kafkasecurity"
 )
 
 type KafkaProducer struct {
 	kafka *sarama.SyncProducer
 }
 
-func NewKafkaProducer(brokers []string) *KafkaProducer {
+func NewKafkaProducer(brokers []string, security *kafkasecurity.Security) *KafkaProducer {
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 5
+	security.Apply(config)
 
 	producer, err := sarama.NewSyncProducer(brokers, config)
 	if err != nil {
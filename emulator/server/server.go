@@ -2,11 +2,13 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/agl/emulator/entities"
 	"github.com/agl/emulator/producers"
+	"github.com/agl/wbtech/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -51,6 +53,24 @@ func (s *Server) produce(ctx *gin.Context) {
         return
     }
 
+    // The order service applies the same rules and would dead-letter the
+    // message, so invalid orders are rejected before they reach Kafka.
+    if err := validation.ValidateOrder(orderBytes); err != nil {
+        var violations validation.Errors
+        if errors.As(err, &violations) {
+            ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+                "error":      "Invalid order",
+                "violations": violations,
+            })
+            return
+        }
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "error":   "Invalid input",
+            "details": err.Error(),
+        })
+        return
+    }
+
     if err := s.kafka.Produce(topic, orderBytes); err != nil {
         ctx.JSON(http.StatusInternalServerError, gin.H{
             "error":   "Failed to produce message",
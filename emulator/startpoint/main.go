@@ -1,16 +1,27 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/agl/emulator/producers"
 	"github.com/agl/emulator/server"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/kafkasecurity"
 )
 
-var brokers = []string {
-	"kafka:9092",
-}
-
 func main() {
-	producer := producers.NewKafkaProducer(brokers)
+	// The emulator reads the same KAFKA_* settings as the order service.
+	cfg, err := config.Load("emulator", os.Args[1:], config.Without("postgres"))
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	security, err := kafkasecurity.New(cfg.Kafka.TLS, cfg.Kafka.SASL)
+	if err != nil {
+		log.Fatalf("Invalid Kafka security settings: %v", err)
+	}
+
+	producer := producers.NewKafkaProducer(cfg.Kafka.Brokers, security)
 	server := server.NewServer(producer)
 
 	server.StartServer()
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/infrastructure/consumers"
+	"github.com/agl/wbtech/internal/infrastructure/deadletter"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/internal/infrastructure/memory"
+	"github.com/agl/wbtech/internal/infrastructure/nats"
+	"github.com/agl/wbtech/internal/infrastructure/producers"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+// ingestion is the producer and consumer of the broker selected by
+// ingest.broker. Dead letters are published to the same broker the orders
+// come from.
+type ingestion struct {
+	producer    interfaces.MessageProducer
+	deadLetters *deadletter.Publisher
+	consumer    interfaces.Consumer
+	close       func()
+}
+
+func connectBroker(ctx context.Context, cfg *config.Config, kafkaCfg kafka.Config) (*ingestion, error) {
+	deadLetterTopic := cfg.Ingest.DeadLetterTopic
+	drainTimeout := cfg.Ingest.DrainTimeout
+
+	switch consumers.Broker(cfg.Ingest.Broker) {
+	case consumers.BrokerNATS:
+		natsCfg, err := nats.NewConfig(cfg.NATS)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := natsCfg.Connect()
+		if err != nil {
+			return nil, err
+		}
+		if err := natsCfg.EnsureStream(ctx, conn, deadLetterTopic); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		producer, err := producers.NewNATSProducer(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		deadLetters := deadletter.NewPublisher(deadLetterTopic, producer)
+		consumer, err := consumers.NewNATSConsumer(natsCfg, conn, drainTimeout, deadLetters)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &ingestion{producer: producer, deadLetters: deadLetters, consumer: consumer, close: conn.Close}, nil
+
+	case consumers.BrokerMemory:
+		broker := memory.NewBroker()
+		// Seed orders go to the first consumed topic only.
+		if seed := cfg.Ingest.MemorySeed; seed != "" {
+			if err := broker.Seed(kafkaCfg.Topics[0], seed); err != nil {
+				return nil, err
+			}
+		}
+		deadLetters := deadletter.NewPublisher(deadLetterTopic, broker)
+		consumer := consumers.NewMemoryConsumer(broker, kafkaCfg.Topics, drainTimeout, deadLetters)
+		return &ingestion{producer: broker, deadLetters: deadLetters, consumer: consumer, close: func() {}}, nil
+
+	default:
+		producer, err := producers.NewKafkaProducer(kafkaCfg)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters := deadletter.NewPublisher(deadLetterTopic, producer)
+		consumer := consumers.NewKafkaConsumer(kafkaCfg, drainTimeout, deadLetters)
+		if consumer == nil {
+			producer.Close()
+			return nil, errors.New("failed to create kafka consumer group")
+		}
+		return &ingestion{producer: producer, deadLetters: deadLetters, consumer: consumer, close: func() { producer.Close() }}, nil
+	}
+}
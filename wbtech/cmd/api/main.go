@@ -1,32 +1,153 @@
 package main
 
 import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/agl/wbtech/internal/application/handlers"
+	"github.com/agl/wbtech/internal/application/interfaces"
 	"github.com/agl/wbtech/internal/application/services"
+	"github.com/agl/wbtech/internal/infrastructure/cache"
 	"github.com/agl/wbtech/internal/infrastructure/consumers"
+	"github.com/agl/wbtech/internal/infrastructure/deadletter"
+	"github.com/agl/wbtech/internal/infrastructure/invalidation"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/internal/infrastructure/monitoring"
 	"github.com/agl/wbtech/internal/infrastructure/repositories"
 	"github.com/agl/wbtech/internal/presentation/controllers"
+	"github.com/agl/wbtech/pkg/config"
 	"github.com/agl/wbtech/pkg/dbconnections"
+	"github.com/agl/wbtech/pkg/logger"
 )
 
-var brokers = []string{
-	"kafka:9092",
-}
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-const groupID = "order-api-group"
+	cfg, err := config.Load("api", os.Args[1:])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	logger.Configure(cfg.Log.Level)
+	logger.Log.Info("Configuration loaded", "config", cfg)
 
-func main() {
-	db_pg := dbconnections.InitPostgres()
+	kafkaCfg, err := kafka.NewConfig(cfg.Kafka)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	db_pg := dbconnections.InitPostgres(cfg.Postgres)
 	defer db_pg.Close()
 
-	repo := repositories.NewOrderRepository(db_pg)
-	service := services.NewOrderService(repo)
-	controller := controllers.NewOrderController(service)
+	cacheCfg := cfg.Cache
+	orderCache, err := cache.New(cacheCfg)
+	if err != nil {
+		log.Fatalf("failed to create order cache: %v", err)
+	}
+	if closer, ok := orderCache.(io.Closer); ok {
+		defer closer.Close()
+	}
+	notFoundCache := cache.NewNegativeCache(cacheCfg.NegativeTTL, cacheCfg.MaxEntries)
+
+	broker := consumers.Broker(cfg.Ingest.Broker)
+	ingest, err := connectBroker(ctx, cfg, kafkaCfg)
+	if err != nil {
+		log.Fatalf("failed to connect to %s: %v", broker, err)
+	}
+	defer ingest.close()
+
+	// Cache invalidation between replicas, dead-letter browsing and lag
+	// polling are only available with Kafka.
+	isKafka := broker == consumers.BrokerKafka
+
+	invalidationCfg := invalidation.NewConfig(cacheCfg.Invalidation)
+	if !isKafka && invalidationCfg.Enabled {
+		logger.Log.Info("Cache invalidation requires kafka, disabling it", "broker", broker)
+		invalidationCfg.Enabled = false
+	}
+	invalidations := invalidation.NewPublisher(invalidationCfg, ingest.producer)
+	if invalidationCfg.Enabled {
+		go invalidation.NewListener(invalidationCfg, kafkaCfg, cache.Local(orderCache), notFoundCache).Listen(ctx)
+	}
+
+	repo := repositories.NewOrderRepository(db_pg, cfg.Orders, cacheCfg.WarmUp, orderCache, notFoundCache, invalidations)
+
+	snapshotter := cache.NewSnapshotter(cacheCfg.Snapshot, orderCache, repo)
+	since, _ := snapshotter.Restore(ctx)
+	repo.WarmUpCache(ctx, since)
+	go snapshotter.Run(ctx)
+
+	service := services.NewOrderService(repo, cfg.Orders.Invariants)
+	cacheService := services.NewCacheService(orderCache, repo, invalidations)
+
+	// Browsing and replaying dead letters reads the Kafka topic directly.
+	var deadLetterService interfaces.DeadLetterService
+	var lagSource *kafka.Config
+	if isKafka {
+		deadLetterStore, err := deadletter.NewStore(kafkaCfg, cfg.Ingest.DeadLetterTopic, ingest.producer)
+		if err != nil {
+			log.Fatalf("failed to create dead-letter store: %v", err)
+		}
+		defer deadLetterStore.Close()
+		deadLetterService = services.NewDeadLetterService(deadLetterStore)
+		lagSource = &kafkaCfg
+	}
+
+	monitor := monitoring.NewConsumerMonitor(cfg.Monitoring, lagSource)
+	go monitor.Run(ctx)
+
+	consumer := ingest.consumer
+	deadLetters := ingest.deadLetters
+	go monitoring.NewDatabaseWatcher(db_pg, consumer, cfg.Postgres.HealthInterval).Run(ctx)
+
+	controller := controllers.NewOrderController(cfg.HTTP, service, cacheService, deadLetterService, monitor, consumer)
+	msg_handler := handlers.NewMessageHandler(cfg.Ingest, consumer, service, deadLetters, monitor)
+
+	handlerDone := make(chan struct{})
+	go func() {
+		defer close(handlerDone)
+		msg_handler.HandleMessage(ctx)
+	}()
+
+	go func() {
+		if err := controller.StartServer(); err != nil {
+			logger.Log.Error("HTTP server failed", "error", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Log.Info("Shutdown signal received")
+	shutdownTimeout := cfg.ShutdownTimeout
+
+	// The consumer stops fetching as soon as ctx is cancelled; waiting for the
+	// handler means in-flight orders are stored and their offsets committed.
+	select {
+	case <-handlerDone:
+	case <-time.After(shutdownTimeout):
+		logger.Log.Warn("Timed out waiting for in-flight messages")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := controller.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Error("Failed to shut down HTTP server", "error", err)
+	}
+
+	if err := consumer.Close(); err != nil {
+		logger.Log.Error("Failed to close consumer", "broker", broker, "error", err)
+	}
 
-	consumer := consumers.NewKafkaConsumer(brokers, groupID)
-	msg_handler := handlers.NewMessageHandler(consumer, service)
+	invalidations.Close(shutdownCtx)
 
-	go msg_handler.HandleMessage()
+	if err := snapshotter.Save(shutdownCtx); err != nil {
+		logger.Log.Error("Failed to save cache snapshot", "error", err)
+	}
 
-	controller.StartServer()
+	logger.Log.Info("Shutdown complete")
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/services"
+	"github.com/agl/wbtech/internal/infrastructure/deadletter"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/internal/infrastructure/producers"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+const usage = `Usage: dlq [-brokers host:port,...] [-topic name] <command> [flags]
+
+Commands:
+  list    -partition N -from OFFSET -limit N    list dead letters with failure reasons
+  show    -partition N -offset OFFSET           print a single dead letter with its payload
+  replay  -partition N -offset OFFSET [-payload FILE|-]
+                                                re-publish a dead letter, optionally edited
+`
+
+func main() {
+	brokers := flag.String("brokers", "", "comma-separated Kafka brokers (default from KAFKA_BROKERS)")
+	topic := flag.String("topic", "", "dead-letter topic (default from DLQ_TOPIC)")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load("dlq", nil, config.Without("postgres"))
+	if err != nil {
+		fail(err)
+	}
+	if *brokers != "" {
+		cfg.Kafka.Brokers = strings.Split(*brokers, ",")
+	}
+	if *topic == "" {
+		*topic = cfg.Ingest.DeadLetterTopic
+	}
+	kafkaCfg, err := kafka.NewConfig(cfg.Kafka)
+	if err != nil {
+		fail(err)
+	}
+
+	producer, err := producers.NewKafkaProducer(kafkaCfg)
+	if err != nil {
+		fail(err)
+	}
+	defer producer.Close()
+
+	store, err := deadletter.NewStore(kafkaCfg, *topic, producer)
+	if err != nil {
+		fail(err)
+	}
+	defer store.Close()
+
+	service := services.NewDeadLetterService(store)
+
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "list":
+		err = list(service, args)
+	case "show":
+		err = show(service, args)
+	case "replay":
+		err = replay(service, args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func list(service *services.DeadLetterService, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	partition := fs.Int("partition", -1, "partition to read, -1 for all")
+	from := fs.Int64("from", 0, "first offset to read")
+	limit := fs.Int("limit", 100, "maximum number of dead letters")
+	fs.Parse(args)
+
+	letters, err := service.List(int32(*partition), *from, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tOFFSET\tKEY\tSTAGE\tFAILED AT\tSOURCE\tERROR")
+	for _, l := range letters {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s/%d/%d\t%s\n",
+			l.Partition, l.Offset, l.Key, l.Stage, l.FailedAt,
+			l.OriginalTopic, l.OriginalPartition, l.OriginalOffset, l.Error)
+	}
+	return w.Flush()
+}
+
+func show(service *services.DeadLetterService, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	partition := fs.Int("partition", 0, "partition of the dead letter")
+	offset := fs.Int64("offset", -1, "offset of the dead letter")
+	fs.Parse(args)
+
+	letter, err := service.Get(int32(*partition), *offset)
+	if err != nil {
+		return err
+	}
+	if letter == nil {
+		return fmt.Errorf("no dead letter at partition %d offset %d", *partition, *offset)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(letter)
+}
+
+func replay(service *services.DeadLetterService, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	partition := fs.Int("partition", 0, "partition of the dead letter")
+	offset := fs.Int64("offset", -1, "offset of the dead letter")
+	payloadPath := fs.String("payload", "", "file with an edited payload, - for stdin")
+	fs.Parse(args)
+
+	req := dto.DeadLetterReplay{Partition: int32(*partition), Offset: *offset}
+	if *payloadPath != "" {
+		payload, err := readPayload(*payloadPath)
+		if err != nil {
+			return err
+		}
+		req.Payload = payload
+	}
+
+	result := service.Replay([]dto.DeadLetterReplay{req})[0]
+	if result.Error != "" {
+		return fmt.Errorf("replay failed: %s", result.Error)
+	}
+	fmt.Printf("replayed partition %d offset %d to %s (edited: %t)\n", result.Partition, result.Offset, result.Topic, result.Edited)
+	return nil
+}
+
+func readPayload(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "dlq:", err)
+	os.Exit(1)
+}
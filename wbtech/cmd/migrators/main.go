@@ -1,13 +1,27 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/agl/wbtech/internal/infrastructure/migrations"
+	"github.com/agl/wbtech/pkg/config"
 	"github.com/agl/wbtech/pkg/dbconnections"
+	"github.com/agl/wbtech/pkg/logger"
 )
 
 func main() {
-	db_pg := dbconnections.InitPostgres()
+	cfg, err := config.Load("migrators", os.Args[1:])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if cfg.Migrations.Path == "" {
+		log.Fatalf("migrations.path is required (MIGRATIONS_PATH)")
+	}
+	logger.Configure(cfg.Log.Level)
+
+	db_pg := dbconnections.InitPostgres(cfg.Postgres)
 	defer db_pg.Close()
 
-	migrations.RunMigrationsPG(db_pg)
+	migrations.RunMigrationsPG(db_pg, cfg.Migrations)
 }
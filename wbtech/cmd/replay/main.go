@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/services"
+	"github.com/agl/wbtech/internal/infrastructure/cache"
+	"github.com/agl/wbtech/internal/infrastructure/invalidation"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/internal/infrastructure/producers"
+	"github.com/agl/wbtech/internal/infrastructure/replay"
+	"github.com/agl/wbtech/internal/infrastructure/repositories"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/dbconnections"
+	"github.com/agl/wbtech/pkg/retry"
+	"github.com/agl/wbtech/pkg/validation"
+)
+
+const usage = `Usage: replay [-topic name] <command> [flags]
+
+Commands:
+  offsets -to earliest|latest|OFFSET|RFC3339 [-partition N] [-apply]
+                                  show, and with -apply commit, new offsets for the
+                                  consumer group (KAFKA_GROUP_ID); its consumers
+                                  must be stopped
+  range   -from RFC3339 [-to RFC3339] [-partition N]
+                                  store every message in the window again through
+                                  the normal storage path; orders stored with
+                                  identical content are skipped, other conflicts
+                                  follow ORDER_CONFLICT_POLICY
+`
+
+func main() {
+	// Postgres is only needed by the range command, which checks for it.
+	cfg, err := config.Load("replay", nil, config.Without("postgres"))
+	if err != nil {
+		fail(err)
+	}
+	kafkaCfg, err := kafka.NewConfig(cfg.Kafka)
+	if err != nil {
+		fail(err)
+	}
+
+	topic := flag.String("topic", kafkaCfg.Topics[0], "topic to reset or replay")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "offsets":
+		err = offsets(kafkaCfg, *topic, args)
+	case "range":
+		err = replayRange(cfg, kafkaCfg, *topic, args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func offsets(kafkaCfg kafka.Config, topic string, args []string) error {
+	fs := flag.NewFlagSet("offsets", flag.ExitOnError)
+	to := fs.String("to", "", "earliest, latest, an offset or an RFC 3339 timestamp")
+	partition := fs.Int("partition", -1, "partition to reset, -1 for all")
+	apply := fs.Bool("apply", false, "commit the new offsets instead of only showing them")
+	fs.Parse(args)
+
+	target, err := replay.ParseTarget(*to)
+	if err != nil {
+		return err
+	}
+
+	resetter, err := replay.NewResetter(kafkaCfg)
+	if err != nil {
+		return err
+	}
+	defer resetter.Close()
+
+	plan, err := resetter.Plan(topic, int32(*partition), target)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tTOPIC\tPARTITION\tCURRENT\tNEW")
+	for _, po := range plan {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", kafkaCfg.GroupID, topic, po.Partition, po.Current, po.Target)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !*apply {
+		fmt.Println("dry run; pass -apply to commit these offsets")
+		return nil
+	}
+	if err := resetter.Apply(topic, plan); err != nil {
+		return err
+	}
+	fmt.Println("offsets committed")
+	return nil
+}
+
+func replayRange(cfg *config.Config, kafkaCfg kafka.Config, topic string, args []string) error {
+	fs := flag.NewFlagSet("range", flag.ExitOnError)
+	fromFlag := fs.String("from", "", "RFC 3339 start of the window (inclusive)")
+	toFlag := fs.String("to", "", "RFC 3339 end of the window (exclusive), default now")
+	partition := fs.Int("partition", -1, "partition to replay, -1 for all")
+	fs.Parse(args)
+
+	from, err := time.Parse(time.RFC3339, *fromFlag)
+	if err != nil {
+		return fmt.Errorf("-from: %w", err)
+	}
+	var to time.Time
+	if *toFlag != "" {
+		if to, err = time.Parse(time.RFC3339, *toFlag); err != nil {
+			return fmt.Errorf("-to: %w", err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db := dbconnections.InitPostgres(cfg.Postgres)
+	defer db.Close()
+
+	// Orders are written through the repository so that replicas drop stale
+	// cache entries; the local cache only lives for the duration of the replay.
+	cacheCfg := cfg.Cache
+	orderCache, err := cache.New(cacheCfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := orderCache.(io.Closer); ok {
+		defer closer.Close()
+	}
+	notFound := cache.NewNegativeCache(cacheCfg.NegativeTTL, cacheCfg.MaxEntries)
+
+	producer, err := producers.NewKafkaProducer(kafkaCfg)
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+	invalidations := invalidation.NewPublisher(invalidation.NewConfig(cacheCfg.Invalidation), producer)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		invalidations.Close(ctx)
+	}()
+
+	repo := repositories.NewOrderRepository(db, cfg.Orders, cacheCfg.WarmUp, orderCache, notFound, invalidations)
+	service := services.NewOrderService(repo, cfg.Orders.Invariants)
+	policy := retry.Policy(cfg.Ingest.Retry)
+
+	replayer, err := replay.NewReplayer(kafkaCfg)
+	if err != nil {
+		return err
+	}
+	defer replayer.Close()
+
+	stats, err := replayer.Run(ctx, topic, int32(*partition), from, to, func(value []byte, _ dto.MessageMetadata) error {
+		// Invalid messages are not retried; the consumer would have sent them
+		// to the dead-letter topic.
+		if err := validation.ValidateOrder(value); err != nil {
+			return err
+		}
+		return retry.Do(ctx, policy, func(int) error {
+			return service.StoreOrder(ctx, value)
+		})
+	})
+	fmt.Printf("replayed %d messages, %d failed\n", stats.Messages, stats.Failed)
+	return err
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "replay:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_items_order_uid;
+DROP INDEX IF EXISTS idx_payment_order_uid;
+DROP INDEX IF EXISTS idx_delivery_order_uid;
+DROP INDEX IF EXISTS idx_orders_recency;
\ No newline at end of file
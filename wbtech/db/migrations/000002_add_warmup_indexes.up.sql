@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_orders_recency ON orders ((COALESCE(date_created, 'epoch'::timestamp)) DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_delivery_order_uid ON delivery (order_uid);
+CREATE INDEX IF NOT EXISTS idx_payment_order_uid ON payment (order_uid);
+CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items (order_uid);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_orders_updated_at ON orders (updated_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS content_hash TEXT;
\ No newline at end of file
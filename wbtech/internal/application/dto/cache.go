@@ -0,0 +1,19 @@
+package dto
+
+type CacheStats struct {
+	Backend     string  `json:"backend"`
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	HitRatio    float64 `json:"hit_ratio"`
+	Evictions   uint64  `json:"evictions"`
+	Expirations uint64  `json:"expirations"`
+	Entries     int     `json:"entries"`
+	ApproxBytes int64   `json:"approx_bytes"`
+	MaxEntries  int     `json:"max_entries"`
+	MaxBytes    int64   `json:"max_bytes"`
+	Policy      string  `json:"policy"`
+	Warming     bool    `json:"warming"`
+
+	Tiers         []CacheStats       `json:"tiers,omitempty"`
+	Invalidations *InvalidationStats `json:"invalidations,omitempty"`
+}
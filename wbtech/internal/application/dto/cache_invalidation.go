@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+const (
+	InvalidationStored  = "stored"
+	InvalidationEvicted = "evicted"
+	InvalidationFlushed = "flushed"
+)
+
+type CacheInvalidation struct {
+	OrderUID string    `json:"order_uid,omitempty"`
+	Reason   string    `json:"reason"`
+	Origin   string    `json:"origin"`
+	At       time.Time `json:"at"`
+}
+
+type InvalidationStats struct {
+	Published uint64 `json:"published"`
+	Failed    uint64 `json:"failed"`
+	Dropped   uint64 `json:"dropped"`
+	Queued    int    `json:"queued"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type PartitionLag struct {
+	Topic         string `json:"topic"`
+	Partition     int32  `json:"partition"`
+	HighWaterMark int64  `json:"high_water_mark"`
+	Committed     int64  `json:"committed"`
+	Lag           int64  `json:"lag"`
+}
+
+type ConsumerStats struct {
+	Group             string         `json:"group"`
+	Partitions        []PartitionLag `json:"partitions"`
+	TotalLag          int64          `json:"total_lag"`
+	LagThreshold      int64          `json:"lag_threshold"`
+	Processed         uint64         `json:"processed"`
+	Failed            uint64         `json:"failed"`
+	MessagesPerSecond float64        `json:"messages_per_second"`
+	AvgLatencyMs      float64        `json:"avg_latency_ms"`
+	MaxLatencyMs      float64        `json:"max_latency_ms"`
+	LagError          string         `json:"lag_error,omitempty"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Healthy           bool           `json:"healthy"`
+}
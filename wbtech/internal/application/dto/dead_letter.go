@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DeadLetter struct {
+	Partition         int32     `json:"partition"`
+	Offset            int64     `json:"offset"`
+	Key               string    `json:"key,omitempty"`
+	Timestamp         time.Time `json:"timestamp"`
+	OriginalTopic     string    `json:"original_topic"`
+	OriginalPartition int32     `json:"original_partition"`
+	OriginalOffset    int64     `json:"original_offset"`
+	Stage             string    `json:"stage"`
+	Error             string    `json:"error"`
+	FailedAt          string    `json:"failed_at"`
+	PayloadSize       int       `json:"payload_size"`
+	Payload           string    `json:"payload,omitempty"`
+}
+
+type DeadLetterReplay struct {
+	Partition int32           `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+type DeadLetterReplayResult struct {
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Topic     string `json:"topic,omitempty"`
+	Edited    bool   `json:"edited"`
+	Error     string `json:"error,omitempty"`
+}
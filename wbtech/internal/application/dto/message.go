@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+type MessageMetadata struct {
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Key       string    `json:"key,omitempty"`
+	OrderUID  string    `json:"order_uid,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -1,14 +1,21 @@
 package dto
 
 type Order struct {
-	TrackNumber     string   `json:"track_number"`
-	Entry           string   `json:"entry"`
-	Delivery        Delivery `json:"delivery"`
-	Payment         Payment  `json:"payment"`
-	Items           []Item   `json:"items"`
-	Locale          string   `json:"locale"`
-	DeliveryService string   `json:"delivery_service"`
-	DateCreated     string   `json:"date_created"`
+	TrackNumber     string    `json:"track_number"`
+	Entry           string    `json:"entry"`
+	Delivery        Delivery  `json:"delivery"`
+	Payment         Payment   `json:"payment"`
+	Items           []Item    `json:"items"`
+	Locale          string    `json:"locale"`
+	DeliveryService string    `json:"delivery_service"`
+	DateCreated     string    `json:"date_created"`
+	Warnings        []Warning `json:"warnings,omitempty"`
+}
+
+type Warning struct {
+	Path    string `json:"path"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 type Delivery struct {
@@ -0,0 +1,9 @@
+package dto
+
+import "time"
+
+type PauseState struct {
+	Paused  bool      `json:"paused"`
+	Reasons []string  `json:"reasons,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+}
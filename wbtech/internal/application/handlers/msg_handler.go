@@ -1,34 +1,148 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/config"
 	"github.com/agl/wbtech/pkg/logger"
+	"github.com/agl/wbtech/pkg/retry"
+	"github.com/agl/wbtech/pkg/validation"
 )
 
 type MessageHandler struct {
-	consumer interfaces.Consumer
-	service  interfaces.OrderService
+	consumer    interfaces.Consumer
+	service     interfaces.OrderService
+	deadLetters interfaces.DeadLetterPublisher
+	retry       retry.Policy
+	workers     config.Ingest
+	metrics     interfaces.IngestMetrics
 }
 
-func NewMessageHandler(consumer interfaces.Consumer, service interfaces.OrderService) *MessageHandler {
+func NewMessageHandler(cfg config.Ingest, consumer interfaces.Consumer, service interfaces.OrderService, deadLetters interfaces.DeadLetterPublisher, metrics interfaces.IngestMetrics) *MessageHandler {
 	return &MessageHandler{
-		consumer: consumer,
-		service:  service,
+		consumer:    consumer,
+		service:     service,
+		deadLetters: deadLetters,
+		retry:       retry.Policy(cfg.Retry),
+		workers:     cfg,
+		metrics:     metrics,
 	}
 }
 
-func (mh *MessageHandler) HandleMessage() {
-	msgChan := make(chan []byte)
+// HandleMessage stores messages on a pool of workers until the consumer closes
+// the channel after ctx is cancelled. Messages already handed over are still
+// processed, but pending retries are abandoned and left for redelivery.
+func (mh *MessageHandler) HandleMessage(ctx context.Context) {
+	msgChan := make(chan interfaces.Message)
+
+	mh.consumer.Consume(ctx, msgChan)
+
+	logger.Log.Info("Start storing the message", "workers", mh.workers.Workers, "queue_size", mh.workers.QueueSize, "ordering", mh.workers.Ordering, "batch_size", mh.workers.BatchSize)
+
+	pool := newWorkerPool(mh.workers, func(msgs []interfaces.Message) {
+		if len(msgs) == 1 {
+			mh.handle(ctx, msgs[0])
+			return
+		}
+		mh.handleBatch(ctx, msgs)
+	})
+	for msg := range msgChan {
+		pool.dispatch(msg)
+	}
+	pool.close()
+
+	logger.Log.Info("Message handler stopped")
+}
+
+// handleBatch stores the messages in one transaction and acknowledges them
+// together. If the batch cannot be stored every message is handled on its own,
+// so a single bad order is retried or dead-lettered without holding back the
+// rest.
+func (mh *MessageHandler) handleBatch(ctx context.Context, msgs []interfaces.Message) {
+	start := time.Now()
+	storeCtx, cancel := context.WithCancel(msgs[0].Context())
+	defer cancel()
+
+	values := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		values[i] = msg.Value()
+		if i > 0 {
+			stop := context.AfterFunc(msg.Context(), cancel)
+			defer stop()
+		}
+	}
 
-	mh.consumer.Consume(msgChan)
+	if err := mh.service.StoreOrders(storeCtx, values); err != nil {
+		logger.Log.Warn("Batch not stored, storing messages one by one", "count", len(msgs), "error", err)
+		for _, msg := range msgs {
+			mh.handle(ctx, msg)
+		}
+		return
+	}
 
-	logger.Log.Info("Start storing the message")
+	latency := time.Since(start)
+	for _, msg := range msgs {
+		msg.Ack()
+		mh.metrics.RecordProcessed(latency)
+	}
+	logger.Log.Info("Message batch stored successfully", "count", len(msgs))
+}
 
-	if err := mh.service.StoreOrder(msgChan); err != nil {
-		logger.Log.Error("Something went wrong during storing the message", "error", err)
+func (mh *MessageHandler) handle(ctx context.Context, msg interfaces.Message) {
+	start := time.Now()
+	if err := mh.process(ctx, msg); err != nil {
+		mh.metrics.RecordFailed(time.Since(start))
+		if errors.Is(err, context.Canceled) {
+			logger.Log.Warn("Retries abandoned on shutdown, message will be redelivered")
+			msg.Nack(err)
+			return
+		}
+		if retry.IsPermanent(err) {
+			logger.Log.Error("Message failed permanently", "error", err)
+		} else {
+			logger.Log.Error("Message not stored after exhausting retries", "attempts", mh.retry.MaxAttempts, "error", err)
+		}
 
+		// Orders rejected by the invariant checks are invalid rather than
+		// failed writes.
+		stage := interfaces.StageStore
+		if errors.As(err, new(validation.Errors)) {
+			stage = interfaces.StageValidate
+		}
+
+		// The message is acknowledged only once it is safely parked in
+		// the dead-letter topic; otherwise it is delivered again.
+		if dlqErr := mh.deadLetters.PublishDeadLetter(msg.Value(), msg.Metadata(), stage, err); dlqErr != nil {
+			msg.Nack(dlqErr)
+			return
+		}
+		msg.Ack()
 		return
 	}
 
+	msg.Ack()
+	mh.metrics.RecordProcessed(time.Since(start))
 	logger.Log.Info("Message stored successfully :)")
 }
+
+// process retries until ctx is cancelled, while each attempt runs under the
+// message's own context so a store already in flight on shutdown can finish.
+func (mh *MessageHandler) process(ctx context.Context, msg interfaces.Message) error {
+	return retry.Do(ctx, mh.retry, func(attempt int) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = retry.Permanent(fmt.Errorf("panic while storing message: %v", r))
+			}
+		}()
+
+		err = mh.service.StoreOrder(msg.Context(), msg.Value())
+		if err != nil && !retry.IsPermanent(err) && attempt < mh.retry.MaxAttempts {
+			logger.Log.Warn("Retrying message after transient failure", "attempt", attempt, "error", err)
+		}
+		return err
+	})
+}
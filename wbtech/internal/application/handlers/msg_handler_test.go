@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/retry"
+	"github.com/agl/wbtech/pkg/validation"
+)
+
+var errTransient = errors.New("connection reset")
+
+type fakeMessage struct {
+	ctx   context.Context
+	value []byte
+	md    dto.MessageMetadata
+
+	mu      sync.Mutex
+	acked   bool
+	nackErr error
+}
+
+func newMessage(uid string) *fakeMessage {
+	return &fakeMessage{
+		ctx:   context.Background(),
+		value: []byte(uid),
+		md:    dto.MessageMetadata{Topic: "orders", OrderUID: uid},
+	}
+}
+
+func (m *fakeMessage) Context() context.Context      { return m.ctx }
+func (m *fakeMessage) Value() []byte                 { return m.value }
+func (m *fakeMessage) Metadata() dto.MessageMetadata { return m.md }
+
+func (m *fakeMessage) Ack() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acked = true
+}
+
+func (m *fakeMessage) Nack(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nackErr = err
+}
+
+// fakeService fails StoreOrder with the next error in errs and StoreOrders
+// with batchErr. Messages are order uids.
+type fakeService struct {
+	mu       sync.Mutex
+	errs     []error
+	batchErr error
+	stored   []string
+	batches  [][]string
+	panics   bool
+}
+
+func (s *fakeService) GetOrderByID(ctx context.Context, id string) (*dto.Order, error) {
+	return nil, nil
+}
+
+func (s *fakeService) StoreOrder(ctx context.Context, msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.panics {
+		panic("nil order")
+	}
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	s.stored = append(s.stored, string(msg))
+	return nil
+}
+
+func (s *fakeService) StoreOrders(ctx context.Context, msgs [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.batchErr != nil {
+		return s.batchErr
+	}
+	var batch []string
+	for _, msg := range msgs {
+		batch = append(batch, string(msg))
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+type deadLetter struct {
+	uid   string
+	stage string
+}
+
+type fakeDeadLetters struct {
+	mu      sync.Mutex
+	err     error
+	letters []deadLetter
+}
+
+func (d *fakeDeadLetters) PublishDeadLetter(value []byte, source dto.MessageMetadata, stage string, cause error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return d.err
+	}
+	d.letters = append(d.letters, deadLetter{uid: string(value), stage: stage})
+	return nil
+}
+
+type fakeMetrics struct {
+	mu        sync.Mutex
+	processed int
+	failed    int
+}
+
+func (m *fakeMetrics) RecordProcessed(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.processed++
+}
+
+func (m *fakeMetrics) RecordFailed(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed++
+}
+
+func testHandler(service *fakeService, deadLetters *fakeDeadLetters) (*MessageHandler, *fakeMetrics) {
+	metrics := &fakeMetrics{}
+	cfg := config.Ingest{Retry: config.Retry{MaxAttempts: 3}}
+	return NewMessageHandler(cfg, nil, service, deadLetters, metrics), metrics
+}
+
+func TestHandleClassifiesFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		errs          []error
+		panics        bool
+		deadLetterErr error
+		cancelled     bool
+		wantStored    bool
+		wantAck       bool
+		wantNack      bool
+		wantStage     string
+		wantRemaining int
+	}{
+		{
+			name:       "stored on the first attempt",
+			wantStored: true,
+			wantAck:    true,
+		},
+		{
+			name:       "retryable failures are retried",
+			errs:       []error{errTransient, errTransient},
+			wantStored: true,
+			wantAck:    true,
+		},
+		{
+			name:      "retries exhausted go to the dead-letter topic",
+			errs:      []error{errTransient, errTransient, errTransient},
+			wantAck:   true,
+			wantStage: interfaces.StageStore,
+		},
+		{
+			name:          "permanent failure is not retried",
+			errs:          []error{retry.Permanent(errTransient), nil},
+			wantAck:       true,
+			wantStage:     interfaces.StageStore,
+			wantRemaining: 1,
+		},
+		{
+			name:          "invariant violations are dead-lettered as invalid",
+			errs:          []error{retry.Permanent(validation.Errors{{Path: "amount", Rule: "total"}}), nil},
+			wantAck:       true,
+			wantStage:     interfaces.StageValidate,
+			wantRemaining: 1,
+		},
+		{
+			name:      "panic is a permanent failure",
+			panics:    true,
+			wantAck:   true,
+			wantStage: interfaces.StageStore,
+		},
+		{
+			name:          "failed dead letter leaves the message for redelivery",
+			errs:          []error{retry.Permanent(errTransient)},
+			deadLetterErr: errors.New("dead-letter topic unavailable"),
+			wantNack:      true,
+		},
+		{
+			name:          "shutdown abandons retries without a dead letter",
+			errs:          []error{errTransient, errTransient},
+			cancelled:     true,
+			wantNack:      true,
+			wantRemaining: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeService{errs: tt.errs, panics: tt.panics}
+			deadLetters := &fakeDeadLetters{err: tt.deadLetterErr}
+			h, metrics := testHandler(service, deadLetters)
+			h.retry.InitialBackoff, h.retry.MaxBackoff = time.Millisecond, time.Millisecond
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+			msg := newMessage("a")
+			h.handle(ctx, msg)
+
+			if stored := len(service.stored) > 0; stored != tt.wantStored {
+				t.Errorf("stored = %v, want %v", stored, tt.wantStored)
+			}
+			if msg.acked != tt.wantAck {
+				t.Errorf("acked = %v, want %v", msg.acked, tt.wantAck)
+			}
+			if (msg.nackErr != nil) != tt.wantNack {
+				t.Errorf("nack = %v, want %v", msg.nackErr, tt.wantNack)
+			}
+			var stage string
+			if len(deadLetters.letters) > 0 {
+				stage = deadLetters.letters[0].stage
+			}
+			if stage != tt.wantStage {
+				t.Errorf("dead-letter stage = %q, want %q", stage, tt.wantStage)
+			}
+			if len(service.errs) != tt.wantRemaining {
+				t.Errorf("%d attempts left unused, want %d", len(service.errs), tt.wantRemaining)
+			}
+			if tt.wantStored && metrics.processed != 1 || !tt.wantStored && metrics.failed != 1 {
+				t.Errorf("processed/failed = %d/%d", metrics.processed, metrics.failed)
+			}
+		})
+	}
+}
+
+func TestHandleBatchFallsBackToSingleMessages(t *testing.T) {
+	errDuplicate := retry.Permanent(errors.New("order appears more than once in batch: a"))
+
+	tests := []struct {
+		name        string
+		batchErr    error
+		errs        []error
+		wantBatches int
+		wantStored  []string
+		wantDead    []string
+	}{
+		{
+			name:        "stored as one batch",
+			wantBatches: 1,
+		},
+		{
+			name:       "duplicate order stores every message on its own",
+			batchErr:   errDuplicate,
+			wantStored: []string{"a", "b", "c"},
+		},
+		{
+			name:       "transient batch failure stores every message on its own",
+			batchErr:   errTransient,
+			wantStored: []string{"a", "b", "c"},
+		},
+		{
+			name:       "bad order is dead-lettered without holding back the rest",
+			batchErr:   errDuplicate,
+			errs:       []error{nil, retry.Permanent(errTransient), nil},
+			wantStored: []string{"a", "c"},
+			wantDead:   []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeService{batchErr: tt.batchErr, errs: tt.errs}
+			deadLetters := &fakeDeadLetters{}
+			h, metrics := testHandler(service, deadLetters)
+
+			msgs := []*fakeMessage{newMessage("a"), newMessage("b"), newMessage("c")}
+			batch := make([]interfaces.Message, len(msgs))
+			for i, msg := range msgs {
+				batch[i] = msg
+			}
+			h.handleBatch(context.Background(), batch)
+
+			if len(service.batches) != tt.wantBatches {
+				t.Errorf("batches = %d, want %d", len(service.batches), tt.wantBatches)
+			}
+			if !slices.Equal(service.stored, tt.wantStored) {
+				t.Errorf("stored one by one = %q, want %q", service.stored, tt.wantStored)
+			}
+			var dead []string
+			for _, letter := range deadLetters.letters {
+				dead = append(dead, letter.uid)
+			}
+			if !slices.Equal(dead, tt.wantDead) {
+				t.Errorf("dead-lettered = %q, want %q", dead, tt.wantDead)
+			}
+			for _, msg := range msgs {
+				if !msg.acked {
+					t.Errorf("message %s not acknowledged", msg.value)
+				}
+			}
+			if metrics.processed+metrics.failed != len(msgs) {
+				t.Errorf("processed/failed = %d/%d for %d messages", metrics.processed, metrics.failed, len(msgs))
+			}
+		})
+	}
+}
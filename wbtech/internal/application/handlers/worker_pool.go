@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+type Ordering string
+
+const (
+	OrderByOrderUID  Ordering = "order_uid"
+	OrderByPartition Ordering = "partition"
+)
+
+// workerPool routes every message to a fixed worker by its ordering key, so
+// messages sharing a key are processed one after another while different keys
+// run in parallel. Each worker has a bounded queue; when it is full dispatch
+// blocks, which in turn stops the consumer from handing over more messages.
+type workerPool struct {
+	cfg    config.Ingest
+	queues []chan interfaces.Message
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(cfg config.Ingest, process func(msgs []interfaces.Message)) *workerPool {
+	p := &workerPool{
+		cfg:    cfg,
+		queues: make([]chan interfaces.Message, cfg.Workers),
+	}
+	for i := range p.queues {
+		queue := make(chan interfaces.Message, cfg.QueueSize)
+		p.queues[i] = queue
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.run(queue, process)
+		}()
+	}
+	return p
+}
+
+func (p *workerPool) run(queue <-chan interfaces.Message, process func(msgs []interfaces.Message)) {
+	var batch []interfaces.Message
+	uids := make(map[string]bool)
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if len(batch) == 0 {
+			return
+		}
+		process(batch)
+		batch = nil
+		clear(uids)
+	}
+
+	for {
+		select {
+		case msg, ok := <-queue:
+			if !ok {
+				flush()
+				return
+			}
+
+			// A batch never holds two versions of the same order, so they
+			// are still written one after the other.
+			uid := msg.Metadata().OrderUID
+			if uid != "" && uids[uid] {
+				flush()
+			}
+			batch = append(batch, msg)
+			uids[uid] = true
+
+			if len(batch) >= p.cfg.BatchSize {
+				flush()
+			} else if timer == nil {
+				timer = time.NewTimer(p.cfg.BatchInterval)
+				timeout = timer.C
+			}
+		case <-timeout:
+			timer, timeout = nil, nil
+			flush()
+		}
+	}
+}
+
+func (p *workerPool) dispatch(msg interfaces.Message) {
+	p.queues[p.worker(msg)] <- msg
+}
+
+func (p *workerPool) worker(msg interfaces.Message) int {
+	md := msg.Metadata()
+
+	h := fnv.New32a()
+	switch {
+	case Ordering(p.cfg.Ordering) == OrderByOrderUID && md.OrderUID != "":
+		h.Write([]byte(md.OrderUID))
+	case Ordering(p.cfg.Ordering) == OrderByOrderUID && md.Key != "":
+		h.Write([]byte(md.Key))
+	default:
+		h.Write([]byte(md.Topic))
+		h.Write([]byte(strconv.Itoa(int(md.Partition))))
+	}
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// close stops accepting messages and waits until every queued one is handled.
+func (p *workerPool) close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+func message(md dto.MessageMetadata) *fakeMessage {
+	msg := newMessage(md.OrderUID)
+	msg.md = md
+	return msg
+}
+
+func TestWorkerPoolKeyAffinity(t *testing.T) {
+	tests := []struct {
+		name     string
+		ordering Ordering
+		a, b     dto.MessageMetadata
+		same     bool
+	}{
+		{
+			name:     "same order on different partitions",
+			ordering: OrderByOrderUID,
+			a:        dto.MessageMetadata{Topic: "orders", Partition: 0, OrderUID: "a"},
+			b:        dto.MessageMetadata{Topic: "orders", Partition: 7, OrderUID: "a"},
+			same:     true,
+		},
+		{
+			name:     "message key when the order uid is unknown",
+			ordering: OrderByOrderUID,
+			a:        dto.MessageMetadata{Topic: "orders", Partition: 0, Key: "a"},
+			b:        dto.MessageMetadata{Topic: "orders", Partition: 7, Key: "a"},
+			same:     true,
+		},
+		{
+			name:     "partition when neither is known",
+			ordering: OrderByOrderUID,
+			a:        dto.MessageMetadata{Topic: "orders", Partition: 3, Offset: 1},
+			b:        dto.MessageMetadata{Topic: "orders", Partition: 3, Offset: 2},
+			same:     true,
+		},
+		{
+			name:     "partition ordering ignores the order uid",
+			ordering: OrderByPartition,
+			a:        dto.MessageMetadata{Topic: "orders", Partition: 3, OrderUID: "a"},
+			b:        dto.MessageMetadata{Topic: "orders", Partition: 3, OrderUID: "b"},
+			same:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &workerPool{
+				cfg:    config.Ingest{Ordering: string(tt.ordering)},
+				queues: make([]chan interfaces.Message, 16),
+			}
+			if same := p.worker(message(tt.a)) == p.worker(message(tt.b)); same != tt.same {
+				t.Errorf("same worker = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolSpreadsKeys(t *testing.T) {
+	p := &workerPool{
+		cfg:    config.Ingest{Ordering: string(OrderByOrderUID)},
+		queues: make([]chan interfaces.Message, 4),
+	}
+
+	used := make(map[int]bool)
+	for i := range 100 {
+		used[p.worker(message(dto.MessageMetadata{OrderUID: fmt.Sprintf("order-%d", i)}))] = true
+	}
+	if len(used) != len(p.queues) {
+		t.Errorf("100 orders used %d of %d workers", len(used), len(p.queues))
+	}
+}
+
+func TestWorkerPoolKeepsOrderPerKey(t *testing.T) {
+	const (
+		keys     = 8
+		versions = 50
+	)
+
+	var mu sync.Mutex
+	inFlight := make(map[string]bool)
+	seen := make(map[string][]int64)
+	process := func(msgs []interfaces.Message) {
+		for _, msg := range msgs {
+			md := msg.Metadata()
+			mu.Lock()
+			if inFlight[md.OrderUID] {
+				t.Errorf("order %s processed concurrently", md.OrderUID)
+			}
+			inFlight[md.OrderUID] = true
+			mu.Unlock()
+
+			time.Sleep(10 * time.Microsecond)
+
+			mu.Lock()
+			inFlight[md.OrderUID] = false
+			seen[md.OrderUID] = append(seen[md.OrderUID], md.Offset)
+			mu.Unlock()
+		}
+	}
+
+	pool := newWorkerPool(config.Ingest{Workers: 4, QueueSize: 2, BatchSize: 1, Ordering: string(OrderByOrderUID)}, process)
+	var offset int64
+	for range versions {
+		for k := range keys {
+			offset++
+			pool.dispatch(message(dto.MessageMetadata{Topic: "orders", Offset: offset, OrderUID: fmt.Sprintf("order-%d", k)}))
+		}
+	}
+	pool.close()
+
+	for k := range keys {
+		uid := fmt.Sprintf("order-%d", k)
+		if len(seen[uid]) != versions {
+			t.Errorf("%s processed %d times, want %d", uid, len(seen[uid]), versions)
+		}
+		if !slices.IsSorted(seen[uid]) {
+			t.Errorf("%s processed out of order: %v", uid, seen[uid])
+		}
+	}
+}
+
+func TestWorkerPoolBatches(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Ingest
+		uids []string
+		want [][]string
+	}{
+		{
+			name: "full batches are flushed",
+			cfg:  config.Ingest{BatchSize: 2, BatchInterval: time.Hour},
+			uids: []string{"a", "b", "c", "d", "e"},
+			want: [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		},
+		{
+			name: "a second version of an order starts a new batch",
+			cfg:  config.Ingest{BatchSize: 10, BatchInterval: time.Hour},
+			uids: []string{"a", "b", "a", "c"},
+			want: [][]string{{"a", "b"}, {"a", "c"}},
+		},
+		{
+			name: "messages without an order uid share a batch",
+			cfg:  config.Ingest{BatchSize: 10, BatchInterval: time.Hour},
+			uids: []string{"", "", "a"},
+			want: [][]string{{"", "", "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches [][]string
+			tt.cfg.Workers, tt.cfg.QueueSize = 1, len(tt.uids)
+			pool := newWorkerPool(tt.cfg, func(msgs []interfaces.Message) {
+				var batch []string
+				for _, msg := range msgs {
+					batch = append(batch, msg.Metadata().OrderUID)
+				}
+				batches = append(batches, batch)
+			})
+			for _, uid := range tt.uids {
+				pool.dispatch(newMessage(uid))
+			}
+			pool.close()
+
+			if !slices.EqualFunc(batches, tt.want, slices.Equal) {
+				t.Errorf("batches = %q, want %q", batches, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolFlushesAfterInterval(t *testing.T) {
+	done := make(chan []interfaces.Message, 1)
+	pool := newWorkerPool(config.Ingest{Workers: 1, QueueSize: 1, BatchSize: 10, BatchInterval: 10 * time.Millisecond}, func(msgs []interfaces.Message) {
+		done <- msgs
+	})
+	defer pool.close()
+
+	pool.dispatch(newMessage("a"))
+	select {
+	case msgs := <-done:
+		if len(msgs) != 1 {
+			t.Errorf("batch of %d, want 1", len(msgs))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("partial batch not flushed after the interval")
+	}
+}
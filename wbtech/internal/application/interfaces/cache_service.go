@@ -0,0 +1,10 @@
+package interfaces
+
+import "github.com/agl/wbtech/internal/application/dto"
+
+type CacheService interface {
+	Stats() dto.CacheStats
+	Evict(orderUID string)
+	Flush()
+	Rewarm() error
+}
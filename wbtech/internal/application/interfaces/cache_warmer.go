@@ -0,0 +1,10 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+type CacheWarmer interface {
+	WarmUpCache(ctx context.Context, updatedSince time.Time)
+}
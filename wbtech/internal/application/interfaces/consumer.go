@@ -1,5 +1,23 @@
 package interfaces
 
+import (
+	"context"
+
+	"github.com/agl/wbtech/internal/application/dto"
+)
+
+type Message interface {
+	// Context is cancelled once the message can no longer be acknowledged,
+	// e.g. some time after its consumer session has ended.
+	Context() context.Context
+	Value() []byte
+	Metadata() dto.MessageMetadata
+	Ack()
+	Nack(err error)
+}
+
 type Consumer interface {
-	Consume(msgChan chan<- []byte)
+	Consume(ctx context.Context, msgChan chan<- Message)
+	Close() error
+	IngestionControl
 }
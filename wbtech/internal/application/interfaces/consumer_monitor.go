@@ -0,0 +1,9 @@
+package interfaces
+
+import "github.com/agl/wbtech/internal/application/dto"
+
+type ConsumerMonitor interface {
+	Stats() dto.ConsumerStats
+	// Ready returns an error describing why the consumer is not keeping up.
+	Ready() error
+}
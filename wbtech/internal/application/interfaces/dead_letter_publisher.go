@@ -0,0 +1,13 @@
+package interfaces
+
+import "github.com/agl/wbtech/internal/application/dto"
+
+const (
+	StageDecode   = "decode"
+	StageValidate = "validate"
+	StageStore    = "store"
+)
+
+type DeadLetterPublisher interface {
+	PublishDeadLetter(value []byte, source dto.MessageMetadata, stage string, cause error) error
+}
@@ -0,0 +1,9 @@
+package interfaces
+
+import "github.com/agl/wbtech/internal/application/dto"
+
+type DeadLetterService interface {
+	List(partition int32, from int64, limit int) ([]dto.DeadLetter, error)
+	Get(partition int32, offset int64) (*dto.DeadLetter, error)
+	Replay(requests []dto.DeadLetterReplay) []dto.DeadLetterReplayResult
+}
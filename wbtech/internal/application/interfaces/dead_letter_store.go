@@ -0,0 +1,9 @@
+package interfaces
+
+import "github.com/agl/wbtech/internal/application/dto"
+
+type DeadLetterStore interface {
+	List(partition int32, from int64, limit int) ([]dto.DeadLetter, error)
+	Get(partition int32, offset int64) (*dto.DeadLetter, error)
+	Republish(letter *dto.DeadLetter, payload []byte) (string, error)
+}
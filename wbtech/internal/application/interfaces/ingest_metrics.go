@@ -0,0 +1,8 @@
+package interfaces
+
+import "time"
+
+type IngestMetrics interface {
+	RecordProcessed(latency time.Duration)
+	RecordFailed(latency time.Duration)
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import "github.com/agl/wbtech/internal/application/dto"
+
+const (
+	PauseReasonAdmin    = "admin"
+	PauseReasonDatabase = "database"
+)
+
+// IngestionControl stops and restarts fetching messages without leaving the
+// consumer group. Ingestion stays paused while any reason is set.
+type IngestionControl interface {
+	Pause(reason string)
+	Resume(reason string)
+	PauseState() dto.PauseState
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/agl/wbtech/internal/application/dto"
+
+type InvalidationPublisher interface {
+	PublishInvalidation(orderUID, reason string)
+	Stats() dto.InvalidationStats
+}
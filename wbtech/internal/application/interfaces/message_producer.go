@@ -0,0 +1,5 @@
+package interfaces
+
+type MessageProducer interface {
+	Produce(topic string, key string, payload []byte, headers map[string]string) error
+}
@@ -0,0 +1,7 @@
+package interfaces
+
+type NegativeCache interface {
+	Contains(key string) bool
+	Add(key string)
+	Delete(key string)
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/domain/entities"
+)
+
+type OrderCache interface {
+	Get(orderUID string) (*entities.Order, bool)
+	Set(order *entities.Order)
+	SetWithTTL(order *entities.Order, ttl time.Duration)
+	Delete(orderUID string)
+	Len() int
+	Range(fn func(order *entities.Order) bool)
+	Flush()
+	Stats() dto.CacheStats
+}
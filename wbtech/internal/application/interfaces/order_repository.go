@@ -1,8 +1,13 @@
 package interfaces
 
-import "github.com/agl/wbtech/internal/domain/entities"
+import (
+	"context"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+)
 
 type OrderRepository interface {
-	GetOrderByID(id string) (*entities.Order, error)
-	StoreOrder(mshChan chan []byte) error
+	GetOrderByID(ctx context.Context, id string) (*entities.Order, error)
+	StoreOrder(ctx context.Context, order *entities.Order) error
+	StoreOrders(ctx context.Context, orders []*entities.Order) error
 }
@@ -1,8 +1,13 @@
 package interfaces
 
-import "github.com/agl/wbtech/internal/application/dto"
+import (
+	"context"
+
+	"github.com/agl/wbtech/internal/application/dto"
+)
 
 type OrderService interface {
-	GetOrderByID(id string) (*dto.Order, error)
-	StoreOrder(msgChan chan []byte) error
+	GetOrderByID(ctx context.Context, id string) (*dto.Order, error)
+	StoreOrder(ctx context.Context, msg []byte) error
+	StoreOrders(ctx context.Context, msgs [][]byte) error
 }
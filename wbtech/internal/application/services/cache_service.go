@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+var ErrWarmUpInProgress = errors.New("cache warm-up is already in progress")
+
+type CacheService struct {
+	cache         interfaces.OrderCache
+	warmer        interfaces.CacheWarmer
+	invalidations interfaces.InvalidationPublisher
+	warming       atomic.Bool
+}
+
+func NewCacheService(cache interfaces.OrderCache, warmer interfaces.CacheWarmer, invalidations interfaces.InvalidationPublisher) *CacheService {
+	return &CacheService{
+		cache:         cache,
+		warmer:        warmer,
+		invalidations: invalidations,
+	}
+}
+
+func (s *CacheService) Stats() dto.CacheStats {
+	stats := s.cache.Stats()
+	stats.Warming = s.warming.Load()
+	invalidations := s.invalidations.Stats()
+	stats.Invalidations = &invalidations
+	return stats
+}
+
+func (s *CacheService) Evict(orderUID string) {
+	s.cache.Delete(orderUID)
+	s.invalidations.PublishInvalidation(orderUID, dto.InvalidationEvicted)
+	logger.Log.Info("Order evicted from cache", "order_uid", orderUID)
+}
+
+func (s *CacheService) Flush() {
+	s.cache.Flush()
+	s.invalidations.PublishInvalidation("", dto.InvalidationFlushed)
+	logger.Log.Info("Order cache flushed")
+}
+
+func (s *CacheService) Rewarm() error {
+	if !s.warming.CompareAndSwap(false, true) {
+		return ErrWarmUpInProgress
+	}
+
+	go func() {
+		defer s.warming.Store(false)
+		s.warmer.WarmUpCache(context.Background(), time.Time{})
+	}()
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+const (
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+var (
+	errDeadLetterNotFound = errors.New("dead letter not found")
+	errInvalidPayload     = errors.New("edited payload is not valid JSON")
+)
+
+type DeadLetterService struct {
+	store interfaces.DeadLetterStore
+}
+
+func NewDeadLetterService(store interfaces.DeadLetterStore) *DeadLetterService {
+	return &DeadLetterService{
+		store: store,
+	}
+}
+
+func (s *DeadLetterService) List(partition int32, from int64, limit int) ([]dto.DeadLetter, error) {
+	if limit <= 0 {
+		limit = defaultDeadLetterLimit
+	}
+	if limit > maxDeadLetterLimit {
+		limit = maxDeadLetterLimit
+	}
+	return s.store.List(partition, from, limit)
+}
+
+func (s *DeadLetterService) Get(partition int32, offset int64) (*dto.DeadLetter, error) {
+	return s.store.Get(partition, offset)
+}
+
+// Replay re-publishes each selected dead letter onto its original topic, using
+// the edited payload when one is given. Every request gets its own result so a
+// single failure does not hide the outcome of the others.
+func (s *DeadLetterService) Replay(requests []dto.DeadLetterReplay) []dto.DeadLetterReplayResult {
+	results := make([]dto.DeadLetterReplayResult, 0, len(requests))
+	for _, req := range requests {
+		result := dto.DeadLetterReplayResult{
+			Partition: req.Partition,
+			Offset:    req.Offset,
+			Edited:    len(req.Payload) > 0,
+		}
+
+		topic, err := s.replay(req)
+		if err != nil {
+			logger.Log.Error("Failed to replay dead letter", "partition", req.Partition, "offset", req.Offset, "error", err)
+			result.Error = err.Error()
+		} else {
+			logger.Log.Info("Dead letter replayed", "partition", req.Partition, "offset", req.Offset, "topic", topic, "edited", result.Edited)
+			result.Topic = topic
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (s *DeadLetterService) replay(req dto.DeadLetterReplay) (string, error) {
+	letter, err := s.store.Get(req.Partition, req.Offset)
+	if err != nil {
+		return "", err
+	}
+	if letter == nil {
+		return "", errDeadLetterNotFound
+	}
+
+	payload := []byte(letter.Payload)
+	if len(req.Payload) > 0 {
+		if !json.Valid(req.Payload) {
+			return "", errInvalidPayload
+		}
+		payload = req.Payload
+	}
+
+	return s.store.Republish(letter, payload)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/validation"
+)
+
+type InvariantMode string
+
+const (
+	// InvariantsStrict rejects orders that break an invariant.
+	InvariantsStrict InvariantMode = "strict"
+	// InvariantsLenient stores them with the violations as warnings.
+	InvariantsLenient InvariantMode = "lenient"
+	InvariantsOff     InvariantMode = "off"
+)
+
+// InvariantConfig is config.Invariants with the checks attached.
+type InvariantConfig config.Invariants
+
+type invariant struct {
+	name  string
+	check func(o *entities.Order, cfg InvariantConfig) []validation.Violation
+}
+
+var invariants = []invariant{
+	{name: "goods_total", check: checkGoodsTotal},
+	{name: "payment_amount", check: checkPaymentAmount},
+	{name: "item_total_price", check: checkItemTotalPrice},
+	{name: "item_track_number", check: checkItemTrackNumber},
+}
+
+// Check returns the violations of every enabled invariant.
+func (c InvariantConfig) Check(o *entities.Order) []validation.Violation {
+	if InvariantMode(c.Mode) == InvariantsOff {
+		return nil
+	}
+
+	var violations []validation.Violation
+	for _, inv := range invariants {
+		if slices.Contains(c.Rules, inv.name) {
+			violations = append(violations, inv.check(o, c)...)
+		}
+	}
+	return violations
+}
+
+func checkGoodsTotal(o *entities.Order, _ InvariantConfig) []validation.Violation {
+	sum := 0
+	for _, item := range o.Items {
+		sum += item.TotalPrice
+	}
+	if o.Payment.GoodsTotal == sum {
+		return nil
+	}
+	return []validation.Violation{{
+		Path:    "$.payment.goods_total",
+		Rule:    "goods_total",
+		Message: fmt.Sprintf("is %d, but the items total %d", o.Payment.GoodsTotal, sum),
+	}}
+}
+
+func checkPaymentAmount(o *entities.Order, _ InvariantConfig) []validation.Violation {
+	p := o.Payment
+	expected := p.GoodsTotal + p.DeliveryCost + p.CustomFee
+	if p.Amount == expected {
+		return nil
+	}
+	return []validation.Violation{{
+		Path:    "$.payment.amount",
+		Rule:    "payment_amount",
+		Message: fmt.Sprintf("is %d, but goods_total + delivery_cost + custom_fee is %d", p.Amount, expected),
+	}}
+}
+
+func checkItemTotalPrice(o *entities.Order, cfg InvariantConfig) []validation.Violation {
+	var violations []validation.Violation
+	for i, item := range o.Items {
+		expected := item.Price * (100 - item.Sale) / 100
+		if diff := item.TotalPrice - expected; diff >= -cfg.PriceTolerance && diff <= cfg.PriceTolerance {
+			continue
+		}
+		violations = append(violations, validation.Violation{
+			Path:    fmt.Sprintf("$.items[%d].total_price", i),
+			Rule:    "item_total_price",
+			Message: fmt.Sprintf("is %d, but price %d with a %d%% sale is %d", item.TotalPrice, item.Price, item.Sale, expected),
+		})
+	}
+	return violations
+}
+
+func checkItemTrackNumber(o *entities.Order, _ InvariantConfig) []validation.Violation {
+	var violations []validation.Violation
+	for i, item := range o.Items {
+		if item.TrackNumber == o.TrackNumber {
+			continue
+		}
+		violations = append(violations, validation.Violation{
+			Path:    fmt.Sprintf("$.items[%d].track_number", i),
+			Rule:    "item_track_number",
+			Message: fmt.Sprintf("is %q, but the order track number is %q", item.TrackNumber, o.TrackNumber),
+		})
+	}
+	return violations
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+)
+
+var allRules = []string{"goods_total", "payment_amount", "item_total_price", "item_track_number"}
+
+// consistentOrder returns an order that keeps every invariant: two items of
+// 453 at 30% off (317.1, rounded down) and 100 at no sale, plus 1500 delivery.
+func consistentOrder() *entities.Order {
+	return &entities.Order{
+		OrderUID:    "a",
+		TrackNumber: "WBILMTESTTRACK",
+		Payment: entities.Payment{
+			Amount:       1917,
+			GoodsTotal:   417,
+			DeliveryCost: 1500,
+		},
+		Items: []entities.Item{
+			{TrackNumber: "WBILMTESTTRACK", Price: 453, Sale: 30, TotalPrice: 317},
+			{TrackNumber: "WBILMTESTTRACK", Price: 100, Sale: 0, TotalPrice: 100},
+		},
+	}
+}
+
+type violation struct {
+	path string
+	rule string
+}
+
+func TestInvariantCheck(t *testing.T) {
+	lenient := InvariantConfig{Mode: string(InvariantsLenient), Rules: allRules, PriceTolerance: 1}
+
+	tests := []struct {
+		name   string
+		cfg    InvariantConfig
+		mutate func(o *entities.Order)
+		want   []violation
+	}{
+		{
+			name:   "consistent order",
+			cfg:    lenient,
+			mutate: func(*entities.Order) {},
+		},
+		{
+			name: "goods total differs from the items",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Payment.GoodsTotal = 400
+				o.Payment.Amount = 1900
+			},
+			want: []violation{{"$.payment.goods_total", "goods_total"}},
+		},
+		{
+			name: "amount differs from goods, delivery and fee",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Payment.CustomFee = 10
+			},
+			want: []violation{{"$.payment.amount", "payment_amount"}},
+		},
+		{
+			name: "custom fee is part of the amount",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Payment.CustomFee = 10
+				o.Payment.Amount += 10
+			},
+		},
+		{
+			name: "total price rounded up within the tolerance",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Items[0].TotalPrice = 318
+				o.Payment.GoodsTotal, o.Payment.Amount = 418, 1918
+			},
+		},
+		{
+			name: "total price below the sale price within the tolerance",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Items[0].TotalPrice = 316
+				o.Payment.GoodsTotal, o.Payment.Amount = 416, 1916
+			},
+		},
+		{
+			name: "total price beyond the tolerance",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Items[1].TotalPrice = 102
+				o.Payment.GoodsTotal, o.Payment.Amount = 419, 1919
+			},
+			want: []violation{{"$.items[1].total_price", "item_total_price"}},
+		},
+		{
+			name:   "zero tolerance still accepts the truncated sale price",
+			cfg:    InvariantConfig{Mode: string(InvariantsStrict), Rules: allRules},
+			mutate: func(*entities.Order) {},
+		},
+		{
+			name: "zero tolerance rejects rounding up",
+			cfg:  InvariantConfig{Mode: string(InvariantsStrict), Rules: allRules},
+			mutate: func(o *entities.Order) {
+				o.Items[0].TotalPrice = 318
+				o.Payment.GoodsTotal, o.Payment.Amount = 418, 1918
+			},
+			want: []violation{{"$.items[0].total_price", "item_total_price"}},
+		},
+		{
+			name: "full sale makes the item free",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Items[1].Sale, o.Items[1].TotalPrice = 100, 0
+				o.Payment.GoodsTotal, o.Payment.Amount = 317, 1817
+			},
+		},
+		{
+			name: "item track number differs from the order",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Items[1].TrackNumber = "OTHER"
+			},
+			want: []violation{{"$.items[1].track_number", "item_track_number"}},
+		},
+		{
+			name: "no items with zero goods total",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Items = nil
+				o.Payment.GoodsTotal, o.Payment.Amount = 0, 1500
+			},
+		},
+		{
+			name: "no items with a goods total",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Items = nil
+			},
+			want: []violation{{"$.payment.goods_total", "goods_total"}},
+		},
+		{
+			name: "every broken invariant is reported",
+			cfg:  lenient,
+			mutate: func(o *entities.Order) {
+				o.Payment.Amount = 0
+				o.Items[0].TotalPrice = 453
+				o.Items[1].TrackNumber = ""
+			},
+			want: []violation{
+				{"$.payment.goods_total", "goods_total"},
+				{"$.payment.amount", "payment_amount"},
+				{"$.items[0].total_price", "item_total_price"},
+				{"$.items[1].track_number", "item_track_number"},
+			},
+		},
+		{
+			name: "only the configured rules run",
+			cfg:  InvariantConfig{Mode: string(InvariantsLenient), Rules: []string{"item_track_number"}},
+			mutate: func(o *entities.Order) {
+				o.Payment.Amount = 0
+				o.Items[1].TrackNumber = ""
+			},
+			want: []violation{{"$.items[1].track_number", "item_track_number"}},
+		},
+		{
+			name: "off mode checks nothing",
+			cfg:  InvariantConfig{Mode: string(InvariantsOff), Rules: allRules},
+			mutate: func(o *entities.Order) {
+				o.Payment.Amount = 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := consistentOrder()
+			tt.mutate(order)
+
+			var got []violation
+			for _, v := range tt.cfg.Check(order) {
+				if v.Message == "" {
+					t.Errorf("%s (%s) has no message", v.Path, v.Rule)
+				}
+				got = append(got, violation{v.Path, v.Rule})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
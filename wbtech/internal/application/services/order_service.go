@@ -1,26 +1,32 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/agl/wbtech/internal/application/dto"
 	"github.com/agl/wbtech/internal/application/interfaces"
 	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
 	"github.com/agl/wbtech/pkg/logger"
+	"github.com/agl/wbtech/pkg/retry"
+	"github.com/agl/wbtech/pkg/validation"
 )
 
 type OrderService struct {
-	repo interfaces.OrderRepository
+	repo       interfaces.OrderRepository
+	invariants InvariantConfig
 }
 
-func NewOrderService(repo interfaces.OrderRepository) *OrderService {
+func NewOrderService(repo interfaces.OrderRepository, invariants config.Invariants) *OrderService {
 	return &OrderService{
-		repo: repo,
+		repo:       repo,
+		invariants: InvariantConfig(invariants),
 	}
 }
 
-func (s *OrderService) GetOrderByID(id string) (*dto.Order, error) {
-	order, err := s.repo.GetOrderByID(id)
+func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*dto.Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -30,9 +36,19 @@ func (s *OrderService) GetOrderByID(id string) (*dto.Order, error) {
 	return ConvertOrderToDTO(order)
 }
 
-func (s *OrderService) StoreOrder(msgChan chan []byte) error {
-	if err := s.repo.StoreOrder(msgChan); err != nil {
-		logger.Log.Error("Failed to store order", "error", err)
+func (s *OrderService) StoreOrder(ctx context.Context, msg []byte) error {
+	var order entities.Order
+	if err := json.Unmarshal(msg, &order); err != nil {
+		logger.Log.Error("Failed to unmarshal order", "error", err)
+
+		return retry.Permanent(err)
+	}
+	if err := s.checkInvariants(&order); err != nil {
+		return err
+	}
+
+	if err := s.repo.StoreOrder(ctx, &order); err != nil {
+		logger.Log.Error("Failed to store order", "order_uid", order.OrderUID, "error", err)
 
 		return err
 	}
@@ -40,6 +56,50 @@ func (s *OrderService) StoreOrder(msgChan chan []byte) error {
 	return nil
 }
 
+// StoreOrders stores the messages as a single batch. Any message that cannot be
+// decoded fails the whole batch.
+func (s *OrderService) StoreOrders(ctx context.Context, msgs [][]byte) error {
+	orders := make([]*entities.Order, len(msgs))
+	for i, msg := range msgs {
+		var order entities.Order
+		if err := json.Unmarshal(msg, &order); err != nil {
+			logger.Log.Error("Failed to unmarshal order", "error", err)
+
+			return retry.Permanent(err)
+		}
+		if err := s.checkInvariants(&order); err != nil {
+			return err
+		}
+		orders[i] = &order
+	}
+
+	return s.repo.StoreOrders(ctx, orders)
+}
+
+// checkInvariants rejects an order that breaks an invariant in strict mode and
+// records the violations on it as warnings in lenient mode. Warnings sent by
+// the producer are never kept.
+func (s *OrderService) checkInvariants(order *entities.Order) error {
+	order.Warnings = nil
+
+	violations := s.invariants.Check(order)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if InvariantMode(s.invariants.Mode) == InvariantsStrict {
+		err := validation.Errors(violations)
+		logger.Log.Error("Order breaks invariants", "order_uid", order.OrderUID, "error", err)
+		return retry.Permanent(err)
+	}
+
+	for _, v := range violations {
+		order.Warnings = append(order.Warnings, entities.Warning{Path: v.Path, Rule: v.Rule, Message: v.Message})
+	}
+	logger.Log.Warn("Storing order with invariant warnings", "order_uid", order.OrderUID, "warnings", len(order.Warnings))
+	return nil
+}
+
 func ConvertOrderToDTO(o *entities.Order) (*dto.Order, error) {
 	b, err := json.Marshal(o)
 	if err != nil {
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/retry"
+	"github.com/agl/wbtech/pkg/validation"
+)
+
+type fakeRepository struct {
+	stored []*entities.Order
+}
+
+func (r *fakeRepository) GetOrderByID(ctx context.Context, id string) (*entities.Order, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) StoreOrder(ctx context.Context, order *entities.Order) error {
+	r.stored = append(r.stored, order)
+	return nil
+}
+
+func (r *fakeRepository) StoreOrders(ctx context.Context, orders []*entities.Order) error {
+	r.stored = append(r.stored, orders...)
+	return nil
+}
+
+func encodeOrder(t *testing.T, o *entities.Order) []byte {
+	t.Helper()
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestStoreOrderInvariantModes(t *testing.T) {
+	inconsistent := func(o *entities.Order) { o.Payment.Amount = 0 }
+	producerWarning := func(o *entities.Order) {
+		o.Warnings = []entities.Warning{{Path: "$.locale", Rule: "forged", Message: "sent by the producer"}}
+	}
+
+	tests := []struct {
+		name         string
+		mode         InvariantMode
+		mutate       func(o *entities.Order)
+		wantRejected bool
+		wantWarnings []string
+	}{
+		{
+			name:   "strict mode stores a consistent order",
+			mode:   InvariantsStrict,
+			mutate: func(*entities.Order) {},
+		},
+		{
+			name:         "strict mode rejects an inconsistent order",
+			mode:         InvariantsStrict,
+			mutate:       inconsistent,
+			wantRejected: true,
+		},
+		{
+			name:         "lenient mode stores the violations as warnings",
+			mode:         InvariantsLenient,
+			mutate:       inconsistent,
+			wantWarnings: []string{"payment_amount"},
+		},
+		{
+			name:   "lenient mode stores a consistent order without warnings",
+			mode:   InvariantsLenient,
+			mutate: func(*entities.Order) {},
+		},
+		{
+			name:   "warnings sent by the producer are dropped",
+			mode:   InvariantsLenient,
+			mutate: producerWarning,
+		},
+		{
+			name: "producer warnings are replaced by the real violations",
+			mode: InvariantsLenient,
+			mutate: func(o *entities.Order) {
+				producerWarning(o)
+				inconsistent(o)
+			},
+			wantWarnings: []string{"payment_amount"},
+		},
+		{
+			name:   "off mode stores an inconsistent order without warnings",
+			mode:   InvariantsOff,
+			mutate: inconsistent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := consistentOrder()
+			tt.mutate(order)
+			msg := encodeOrder(t, order)
+
+			single := func(s *OrderService) error { return s.StoreOrder(context.Background(), msg) }
+			batch := func(s *OrderService) error { return s.StoreOrders(context.Background(), [][]byte{msg}) }
+			for name, store := range map[string]func(*OrderService) error{"single": single, "batch": batch} {
+				repo := &fakeRepository{}
+				service := NewOrderService(repo, config.Invariants{Mode: string(tt.mode), Rules: allRules, PriceTolerance: 1})
+				err := store(service)
+
+				if tt.wantRejected {
+					var violations validation.Errors
+					if !retry.IsPermanent(err) || !errors.As(err, &violations) {
+						t.Errorf("%s: err = %v, want permanent validation errors", name, err)
+					}
+					if len(repo.stored) != 0 {
+						t.Errorf("%s: rejected order was stored", name)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if len(repo.stored) != 1 {
+					t.Fatalf("%s: stored %d orders, want 1", name, len(repo.stored))
+				}
+				var rules []string
+				for _, w := range repo.stored[0].Warnings {
+					rules = append(rules, w.Rule)
+				}
+				if !slices.Equal(rules, tt.wantWarnings) {
+					t.Errorf("%s: warnings = %q, want %q", name, rules, tt.wantWarnings)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreOrderRejectsInvalidJSON(t *testing.T) {
+	repo := &fakeRepository{}
+	service := NewOrderService(repo, config.Invariants{Mode: string(InvariantsOff)})
+
+	if err := service.StoreOrder(context.Background(), []byte("{")); !retry.IsPermanent(err) {
+		t.Errorf("StoreOrder err = %v, want permanent", err)
+	}
+	if err := service.StoreOrders(context.Background(), [][]byte{encodeOrder(t, consistentOrder()), []byte("{")}); !retry.IsPermanent(err) {
+		t.Errorf("StoreOrders err = %v, want permanent", err)
+	}
+	if len(repo.stored) != 0 {
+		t.Errorf("stored %d orders from invalid messages", len(repo.stored))
+	}
+}
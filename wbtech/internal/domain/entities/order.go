@@ -15,6 +15,15 @@ type Order struct {
 	SmID              int      `json:"sm_id"`
 	DateCreated       string   `json:"date_created"`
 	OofShard          string   `json:"oof_shard"`
+	// Warnings lists the invariants the order broke when it was stored in
+	// lenient mode.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+type Warning struct {
+	Path    string `json:"path"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 type Delivery struct {
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+func New(cfg config.Cache) (interfaces.OrderCache, error) {
+	switch Backend(cfg.Backend) {
+	case BackendRedis:
+		return newRedisFromConfig(cfg)
+	case BackendTiered:
+		l2, err := newRedisFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		l1TTL := cfg.L1TTL
+		if cfg.TTL > 0 && (l1TTL <= 0 || cfg.TTL < l1TTL) {
+			l1TTL = cfg.TTL
+		}
+		return NewTieredCache(NewMemoryCache(cfg), l2, l1TTL), nil
+	default:
+		return NewMemoryCache(cfg), nil
+	}
+}
+
+// Local returns the part of c held by this process, which is all other
+// replicas' invalidations have to reach: the whole cache for memory, the L1
+// for tiered and nil for redis, which the replicas share.
+func Local(c interfaces.OrderCache) interfaces.OrderCache {
+	switch c := c.(type) {
+	case *TieredCache:
+		return c.l1
+	case *RedisCache:
+		return nil
+	default:
+		return c
+	}
+}
+
+func newRedisFromConfig(cfg config.Cache) (*RedisCache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping redis at %s: %w", cfg.Redis.Addr, err)
+	}
+
+	logger.Log.Info("Connected to redis cache", "addr", cfg.Redis.Addr, "db", cfg.Redis.DB, "prefix", cfg.Redis.Prefix)
+
+	return NewRedisCache(client, cfg.Redis.Prefix, cfg.TTL), nil
+}
@@ -0,0 +1,16 @@
+package cache
+
+type Backend string
+
+const (
+	BackendMemory Backend = "memory"
+	BackendRedis  Backend = "redis"
+	BackendTiered Backend = "tiered"
+)
+
+type Policy string
+
+const (
+	PolicyLRU Policy = "lru"
+	PolicyLFU Policy = "lfu"
+)
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+type entry struct {
+	key       string
+	order     *entities.Order
+	size      int64
+	expiresAt time.Time
+
+	elem     *list.Element
+	index    int
+	freq     uint64
+	lastUsed uint64
+	// expiryIndex is the position in the expiry heap, -1 without a TTL.
+	expiryIndex int
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+type MemoryCache struct {
+	mu      sync.Mutex
+	cfg     config.Cache
+	entries map[string]*entry
+	policy  evictionPolicy
+	expiry  expiryHeap
+	bytes   int64
+	now     func() time.Time
+
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+}
+
+func NewMemoryCache(cfg config.Cache) *MemoryCache {
+	return &MemoryCache{
+		cfg:     cfg,
+		entries: make(map[string]*entry),
+		policy:  newEvictionPolicy(Policy(cfg.Policy)),
+		now:     time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(orderUID string) (*entities.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[orderUID]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	if e.expired(c.now()) {
+		c.removeEntry(e)
+		c.expirations++
+		c.misses++
+		return nil, false
+	}
+	c.policy.touch(e)
+	c.hits++
+
+	return e.order, true
+}
+
+func (c *MemoryCache) Set(order *entities.Order) {
+	c.SetWithTTL(order, c.cfg.TTL)
+}
+
+func (c *MemoryCache) SetWithTTL(order *entities.Order, ttl time.Duration) {
+	if order == nil || order.OrderUID == "" {
+		return
+	}
+
+	e := &entry{
+		key:         order.OrderUID,
+		order:       order,
+		size:        estimateSize(order),
+		expiryIndex: -1,
+	}
+
+	if c.cfg.MaxBytes > 0 && e.size > c.cfg.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.entries[e.key]; ok {
+		c.removeEntry(old)
+	}
+
+	c.entries[e.key] = e
+	c.bytes += e.size
+	c.policy.add(e)
+	if ttl > 0 {
+		e.expiresAt = c.now().Add(ttl)
+		heap.Push(&c.expiry, e)
+	}
+
+	c.evict()
+}
+
+func (c *MemoryCache) Delete(orderUID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[orderUID]; ok {
+		c.removeEntry(e)
+	}
+}
+
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+func (c *MemoryCache) Range(fn func(order *entities.Order) bool) {
+	c.mu.Lock()
+	now := c.now()
+	orders := make([]*entities.Order, 0, len(c.entries))
+	for _, e := range c.entries {
+		if !e.expired(now) {
+			orders = append(orders, e.order)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, order := range orders {
+		if !fn(order) {
+			return
+		}
+	}
+}
+
+func (c *MemoryCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*entry)
+	c.policy.reset()
+	c.expiry = nil
+	c.bytes = 0
+}
+
+func (c *MemoryCache) Stats() dto.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := dto.CacheStats{
+		Backend:     string(BackendMemory),
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+		Entries:     len(c.entries),
+		ApproxBytes: c.bytes,
+		MaxEntries:  c.cfg.MaxEntries,
+		MaxBytes:    c.cfg.MaxBytes,
+		Policy:      c.cfg.Policy,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRatio = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+func (c *MemoryCache) overLimit() bool {
+	if c.cfg.MaxEntries > 0 && len(c.entries) > c.cfg.MaxEntries {
+		return true
+	}
+	if c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes {
+		return true
+	}
+	return false
+}
+
+// evict drops expired entries before evicting live ones by the policy. The
+// expiry heap keeps this proportional to the entries removed rather than the
+// size of the cache.
+func (c *MemoryCache) evict() {
+	if !c.overLimit() {
+		return
+	}
+
+	now := c.now()
+	for len(c.expiry) > 0 && c.expiry[0].expired(now) {
+		c.removeEntry(c.expiry[0])
+		c.expirations++
+	}
+
+	for c.overLimit() {
+		victim := c.policy.victim()
+		if victim == nil {
+			return
+		}
+		c.removeEntry(victim)
+		c.evictions++
+	}
+}
+
+func (c *MemoryCache) removeEntry(e *entry) {
+	c.policy.remove(e)
+	if e.expiryIndex >= 0 {
+		heap.Remove(&c.expiry, e.expiryIndex)
+	}
+	delete(c.entries, e.key)
+	c.bytes -= e.size
+}
+
+// expiryHeap orders the entries with a TTL by expiry time, soonest first.
+type expiryHeap []*entry
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].expiryIndex = i
+	h[j].expiryIndex = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	e := x.(*entry)
+	e.expiryIndex = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.expiryIndex = -1
+	*h = old[:n-1]
+	return e
+}
@@ -0,0 +1,363 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+// testCache returns a memory cache with a clock that only moves when advance
+// is called.
+func testCache(cfg config.Cache) (*MemoryCache, func(time.Duration)) {
+	c := NewMemoryCache(cfg)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func order(uid string) *entities.Order {
+	return &entities.Order{OrderUID: uid}
+}
+
+func set(c *MemoryCache, uids ...string) {
+	for _, uid := range uids {
+		c.Set(order(uid))
+	}
+}
+
+func get(c *MemoryCache, uids ...string) {
+	for _, uid := range uids {
+		c.Get(uid)
+	}
+}
+
+func keys(c *MemoryCache) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var uids []string
+	for uid := range c.entries {
+		uids = append(uids, uid)
+	}
+	slices.Sort(uids)
+	return uids
+}
+
+func TestMemoryCacheEvictionOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		run    func(c *MemoryCache)
+		want   []string
+	}{
+		{
+			name:   "lru evicts the least recently read",
+			policy: PolicyLRU,
+			run: func(c *MemoryCache) {
+				set(c, "a", "b", "c")
+				get(c, "a")
+				set(c, "d")
+			},
+			want: []string{"a", "c", "d"},
+		},
+		{
+			name:   "lru counts a rewrite as a use",
+			policy: PolicyLRU,
+			run: func(c *MemoryCache) {
+				set(c, "a", "b", "c", "a", "d")
+			},
+			want: []string{"a", "c", "d"},
+		},
+		{
+			name:   "lru without reads evicts in insertion order",
+			policy: PolicyLRU,
+			run: func(c *MemoryCache) {
+				set(c, "a", "b", "c", "d", "e")
+			},
+			want: []string{"c", "d", "e"},
+		},
+		{
+			name:   "lfu evicts the least frequently read",
+			policy: PolicyLFU,
+			run: func(c *MemoryCache) {
+				set(c, "a", "b", "c")
+				get(c, "a", "a", "b")
+				set(c, "d")
+			},
+			// c and d were both used once; c longer ago.
+			want: []string{"a", "b", "d"},
+		},
+		{
+			name:   "lfu breaks ties by the oldest access",
+			policy: PolicyLFU,
+			run: func(c *MemoryCache) {
+				set(c, "a", "b", "c", "d")
+				get(c, "b")
+				set(c, "e")
+			},
+			want: []string{"b", "d", "e"},
+		},
+		{
+			name:   "lfu evicts a new entry before frequently read ones",
+			policy: PolicyLFU,
+			run: func(c *MemoryCache) {
+				set(c, "a", "b", "c")
+				get(c, "a", "b", "c")
+				set(c, "d")
+			},
+			want: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testCache(config.Cache{MaxEntries: 3, Policy: string(tt.policy)})
+			tt.run(c)
+
+			if got := keys(c); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	tests := []struct {
+		name            string
+		cfg             config.Cache
+		run             func(c *MemoryCache, advance func(time.Duration))
+		want            []string
+		wantExpirations uint64
+		wantEvictions   uint64
+	}{
+		{
+			name: "entry is served until its ttl",
+			cfg:  config.Cache{TTL: time.Minute},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				set(c, "a")
+				advance(time.Minute)
+				if _, ok := c.Get("a"); !ok {
+					t.Error("a expired at its ttl")
+				}
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "expired entry is a miss and removed on read",
+			cfg:  config.Cache{TTL: time.Minute},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				set(c, "a")
+				advance(time.Minute + time.Second)
+				if _, ok := c.Get("a"); ok {
+					t.Error("a served after its ttl")
+				}
+			},
+			wantExpirations: 1,
+		},
+		{
+			name: "zero ttl never expires",
+			cfg:  config.Cache{},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				set(c, "a")
+				advance(24 * time.Hour)
+				get(c, "a")
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "per-entry ttl overrides the default",
+			cfg:  config.Cache{TTL: time.Hour},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				c.SetWithTTL(order("a"), time.Second)
+				set(c, "b")
+				advance(time.Minute)
+				get(c, "a", "b")
+			},
+			want:            []string{"b"},
+			wantExpirations: 1,
+		},
+		{
+			name: "expired entries make room before live ones are evicted",
+			cfg:  config.Cache{MaxEntries: 2},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				c.SetWithTTL(order("a"), time.Second)
+				set(c, "b")
+				advance(time.Minute)
+				set(c, "c")
+			},
+			want:            []string{"b", "c"},
+			wantExpirations: 1,
+		},
+		{
+			name: "live entries are evicted when nothing has expired",
+			cfg:  config.Cache{MaxEntries: 2},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				c.SetWithTTL(order("a"), time.Hour)
+				c.SetWithTTL(order("b"), time.Hour)
+				advance(time.Minute)
+				c.SetWithTTL(order("c"), time.Hour)
+			},
+			want:          []string{"b", "c"},
+			wantEvictions: 1,
+		},
+		{
+			name: "rewrite replaces the expiry",
+			cfg:  config.Cache{MaxEntries: 2},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				c.SetWithTTL(order("a"), time.Second)
+				c.SetWithTTL(order("a"), time.Hour)
+				set(c, "b")
+				advance(time.Minute)
+				set(c, "c")
+			},
+			want:          []string{"b", "c"},
+			wantEvictions: 1,
+		},
+		{
+			name: "deleted and flushed entries leave the expiry heap",
+			cfg:  config.Cache{MaxEntries: 1},
+			run: func(c *MemoryCache, advance func(time.Duration)) {
+				c.SetWithTTL(order("a"), time.Second)
+				c.Delete("a")
+				c.SetWithTTL(order("b"), time.Second)
+				c.Flush()
+				c.SetWithTTL(order("c"), time.Second)
+				advance(time.Minute)
+				set(c, "d")
+			},
+			want:            []string{"d"},
+			wantExpirations: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, advance := testCache(tt.cfg)
+			tt.run(c, advance)
+
+			if got := keys(c); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+			stats := c.Stats()
+			if stats.Expirations != tt.wantExpirations {
+				t.Errorf("expirations = %d, want %d", stats.Expirations, tt.wantExpirations)
+			}
+			if stats.Evictions != tt.wantEvictions {
+				t.Errorf("evictions = %d, want %d", stats.Evictions, tt.wantEvictions)
+			}
+			if len(c.expiry) > len(c.entries) {
+				t.Errorf("expiry heap holds %d entries for %d cached", len(c.expiry), len(c.entries))
+			}
+		})
+	}
+}
+
+func TestMemoryCacheLimits(t *testing.T) {
+	size := estimateSize(order("a"))
+
+	warnings := []entities.Warning{
+		{Path: "$.payment.amount", Rule: "payment_amount", Message: "amount does not match the goods, delivery and fee"},
+		{Path: "$.items[0].total_price", Rule: "item_total_price", Message: "total price does not match the price with the sale applied"},
+	}
+	warned := func(uid string) *entities.Order {
+		o := order(uid)
+		o.Warnings = slices.Clip(slices.Clone(warnings))
+		return o
+	}
+	warnedSize := size + int64(len(warnings))*int64(unsafe.Sizeof(entities.Warning{}))
+	for _, w := range warnings {
+		warnedSize += int64(len(w.Path) + len(w.Rule) + len(w.Message))
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.Cache
+		uids      []string
+		warned    bool
+		wantLen   int
+		wantBytes int64
+	}{
+		{
+			name:      "no limits",
+			cfg:       config.Cache{},
+			uids:      []string{"a", "b", "c", "d"},
+			wantLen:   4,
+			wantBytes: 4 * size,
+		},
+		{
+			name:      "entry limit",
+			cfg:       config.Cache{MaxEntries: 2},
+			uids:      []string{"a", "b", "c", "d"},
+			wantLen:   2,
+			wantBytes: 2 * size,
+		},
+		{
+			name:      "byte limit",
+			cfg:       config.Cache{MaxBytes: 3*size - 1},
+			uids:      []string{"a", "b", "c", "d"},
+			wantLen:   2,
+			wantBytes: 2 * size,
+		},
+		{
+			name:      "tighter of both limits wins",
+			cfg:       config.Cache{MaxEntries: 3, MaxBytes: 2 * size},
+			uids:      []string{"a", "b", "c", "d"},
+			wantLen:   2,
+			wantBytes: 2 * size,
+		},
+		{
+			name:    "order larger than the byte limit is not cached",
+			cfg:     config.Cache{MaxBytes: size - 1},
+			uids:    []string{"a"},
+			wantLen: 0,
+		},
+		{
+			name:      "rewriting an order does not count it twice",
+			cfg:       config.Cache{MaxEntries: 2},
+			uids:      []string{"a", "a", "a"},
+			wantLen:   1,
+			wantBytes: size,
+		},
+		{
+			name:      "warnings count towards the size",
+			cfg:       config.Cache{},
+			uids:      []string{"a", "b"},
+			warned:    true,
+			wantLen:   2,
+			wantBytes: 2 * warnedSize,
+		},
+		{
+			name:      "warnings count towards the byte limit",
+			cfg:       config.Cache{MaxBytes: 2 * size},
+			uids:      []string{"a", "b"},
+			warned:    true,
+			wantLen:   1,
+			wantBytes: warnedSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testCache(tt.cfg)
+			if tt.warned {
+				for _, uid := range tt.uids {
+					c.Set(warned(uid))
+				}
+			} else {
+				set(c, tt.uids...)
+			}
+
+			stats := c.Stats()
+			if stats.Entries != tt.wantLen {
+				t.Errorf("entries = %d, want %d", stats.Entries, tt.wantLen)
+			}
+			if stats.ApproxBytes != tt.wantBytes {
+				t.Errorf("bytes = %d, want %d", stats.ApproxBytes, tt.wantBytes)
+			}
+		})
+	}
+}
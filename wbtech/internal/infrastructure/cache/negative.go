@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultNegativeMaxEntries = 10000
+
+// NegativeCache remembers keys that were recently looked up and not found, so
+// repeated probes for them do not reach the database until the TTL runs out.
+type NegativeCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]time.Time
+}
+
+func NewNegativeCache(ttl time.Duration, maxEntries int) *NegativeCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultNegativeMaxEntries
+	}
+	return &NegativeCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]time.Time),
+	}
+}
+
+func (c *NegativeCache) Contains(key string) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiresAt) {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+func (c *NegativeCache) Add(key string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= c.maxEntries {
+		for k, expiresAt := range c.entries {
+			if now.After(expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		return
+	}
+
+	c.entries[key] = now.Add(c.ttl)
+}
+
+func (c *NegativeCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
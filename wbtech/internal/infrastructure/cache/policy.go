@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+type evictionPolicy interface {
+	add(e *entry)
+	touch(e *entry)
+	remove(e *entry)
+	victim() *entry
+	reset()
+}
+
+func newEvictionPolicy(p Policy) evictionPolicy {
+	if p == PolicyLFU {
+		return &lfuPolicy{}
+	}
+	return &lruPolicy{order: list.New()}
+}
+
+type lruPolicy struct {
+	order *list.List
+}
+
+func (p *lruPolicy) add(e *entry) {
+	e.elem = p.order.PushFront(e)
+}
+
+func (p *lruPolicy) touch(e *entry) {
+	p.order.MoveToFront(e.elem)
+}
+
+func (p *lruPolicy) remove(e *entry) {
+	p.order.Remove(e.elem)
+	e.elem = nil
+}
+
+func (p *lruPolicy) victim() *entry {
+	back := p.order.Back()
+	if back == nil {
+		return nil
+	}
+	return back.Value.(*entry)
+}
+
+func (p *lruPolicy) reset() {
+	p.order.Init()
+}
+
+// lfuPolicy evicts the least frequently used entry, breaking ties by the
+// oldest access so that a burst of one-off reads does not pin stale entries.
+type lfuPolicy struct {
+	entries lfuHeap
+	tick    uint64
+}
+
+func (p *lfuPolicy) add(e *entry) {
+	p.tick++
+	e.freq = 1
+	e.lastUsed = p.tick
+	heap.Push(&p.entries, e)
+}
+
+func (p *lfuPolicy) touch(e *entry) {
+	p.tick++
+	e.freq++
+	e.lastUsed = p.tick
+	heap.Fix(&p.entries, e.index)
+}
+
+func (p *lfuPolicy) remove(e *entry) {
+	heap.Remove(&p.entries, e.index)
+}
+
+func (p *lfuPolicy) victim() *entry {
+	if len(p.entries) == 0 {
+		return nil
+	}
+	return p.entries[0]
+}
+
+func (p *lfuPolicy) reset() {
+	p.entries = nil
+	p.tick = 0
+}
+
+type lfuHeap []*entry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].lastUsed < h[j].lastUsed
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
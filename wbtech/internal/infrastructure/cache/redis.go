@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisOpTimeout   = time.Second
+	redisScanTimeout = time.Minute
+	redisScanCount   = 1000
+)
+
+// RedisCache stores orders as JSON values under a key prefix. Eviction is left
+// to the server's maxmemory policy; TTLs are applied per key.
+type RedisCache struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func NewRedisCache(client redis.UniversalClient, prefix string, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (c *RedisCache) key(orderUID string) string {
+	return c.prefix + orderUID
+}
+
+func (c *RedisCache) Get(orderUID string) (*entities.Order, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	b, err := c.client.Get(ctx, c.key(orderUID)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Log.Error("Failed to get order from redis", "order_uid", orderUID, "error", err)
+		}
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	var order entities.Order
+	if err := json.Unmarshal(b, &order); err != nil {
+		logger.Log.Error("Failed to decode order from redis", "order_uid", orderUID, "error", err)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return &order, true
+}
+
+func (c *RedisCache) Set(order *entities.Order) {
+	c.SetWithTTL(order, c.ttl)
+}
+
+func (c *RedisCache) SetWithTTL(order *entities.Order, ttl time.Duration) {
+	if order == nil || order.OrderUID == "" {
+		return
+	}
+
+	b, err := json.Marshal(order)
+	if err != nil {
+		logger.Log.Error("Failed to encode order for redis", "order_uid", order.OrderUID, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	if err := c.client.Set(ctx, c.key(order.OrderUID), b, ttl).Err(); err != nil {
+		logger.Log.Error("Failed to set order in redis", "order_uid", order.OrderUID, "error", err)
+	}
+}
+
+func (c *RedisCache) Delete(orderUID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	if err := c.client.Del(ctx, c.key(orderUID)).Err(); err != nil {
+		logger.Log.Error("Failed to delete order from redis", "order_uid", orderUID, "error", err)
+	}
+}
+
+// Len scans the keyspace, so it is only meant for one-off reporting such as
+// the end of a warm-up.
+func (c *RedisCache) Len() int {
+	n := 0
+	c.scan(func(ctx context.Context, keys []string) error {
+		n += len(keys)
+		return nil
+	})
+	return n
+}
+
+func (c *RedisCache) Range(fn func(order *entities.Order) bool) {
+	stop := errors.New("stop")
+	err := c.scan(func(ctx context.Context, keys []string) error {
+		values, err := c.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+			var order entities.Order
+			if err := json.Unmarshal([]byte(s), &order); err != nil {
+				logger.Log.Error("Failed to decode order from redis", "error", err)
+				continue
+			}
+			if !fn(&order) {
+				return stop
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, stop) {
+		logger.Log.Error("Failed to range over redis orders", "error", err)
+	}
+}
+
+func (c *RedisCache) Flush() {
+	err := c.scan(func(ctx context.Context, keys []string) error {
+		return c.client.Del(ctx, keys...).Err()
+	})
+	if err != nil {
+		logger.Log.Error("Failed to flush redis orders", "error", err)
+	}
+}
+
+// Stats leaves Entries empty: counting the orders takes a scan of the
+// keyspace, which is too expensive for every metrics scrape.
+func (c *RedisCache) Stats() dto.CacheStats {
+	stats := dto.CacheStats{
+		Backend: string(BackendRedis),
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+func (c *RedisCache) scan(fn func(ctx context.Context, keys []string) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisScanTimeout)
+	defer cancel()
+
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, c.prefix+"*", redisScanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(ctx, keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testPrefix = "order:"
+
+func testRedis(t *testing.T, ttl time.Duration) (*miniredis.Miniredis, *RedisCache) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	// No retries, so tests against a stopped server fail fast.
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return server, NewRedisCache(client, testPrefix, ttl)
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		run     func(c *RedisCache)
+		advance time.Duration
+		want    bool
+	}{
+		{
+			name:    "entry is served until its ttl",
+			ttl:     time.Minute,
+			run:     func(c *RedisCache) { c.Set(order("a")) },
+			advance: time.Minute - time.Second,
+			want:    true,
+		},
+		{
+			name:    "entry expires after its ttl",
+			ttl:     time.Minute,
+			run:     func(c *RedisCache) { c.Set(order("a")) },
+			advance: time.Minute,
+		},
+		{
+			name:    "zero ttl never expires",
+			run:     func(c *RedisCache) { c.Set(order("a")) },
+			advance: 24 * time.Hour,
+			want:    true,
+		},
+		{
+			name:    "per-entry ttl overrides the default",
+			ttl:     time.Hour,
+			run:     func(c *RedisCache) { c.SetWithTTL(order("a"), time.Second) },
+			advance: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, c := testRedis(t, tt.ttl)
+			tt.run(c)
+			server.FastForward(tt.advance)
+
+			if _, ok := c.Get("a"); ok != tt.want {
+				t.Errorf("cached = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisCacheKeys(t *testing.T) {
+	server, c := testRedis(t, 0)
+	server.Set("other:a", "kept")
+
+	c.Set(order("a"))
+	c.Set(order("b"))
+	if !server.Exists(testPrefix + "a") {
+		t.Fatalf("order not stored under the %q prefix", testPrefix)
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("len = %d, want 2", n)
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("deleted order still cached")
+	}
+
+	c.Flush()
+	if n := c.Len(); n != 0 {
+		t.Errorf("len after flush = %d, want 0", n)
+	}
+	if !server.Exists("other:a") {
+		t.Error("flush removed a key outside the prefix")
+	}
+}
+
+func TestRedisCacheStatsDoesNotScan(t *testing.T) {
+	server, c := testRedis(t, 0)
+	c.Set(order("a"))
+	c.Get("a")
+	c.Get("b")
+
+	before := server.CommandCount()
+	stats := c.Stats()
+	if n := server.CommandCount() - before; n != 0 {
+		t.Errorf("stats sent %d commands to redis", n)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("hits/misses = %d/%d, want 1/1", stats.Hits, stats.Misses)
+	}
+}
+
+func TestRedisCacheDown(t *testing.T) {
+	server, c := testRedis(t, 0)
+	c.Set(order("a"))
+	server.Close()
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("order served while redis is down")
+	}
+	// Writes and deletes are logged and dropped.
+	c.Set(order("b"))
+	c.Delete("a")
+	c.Flush()
+
+	if n := c.Len(); n != 0 {
+		t.Errorf("len = %d, want 0", n)
+	}
+	if stats := c.Stats(); stats.Misses != 1 {
+		t.Errorf("misses = %d, want 1", stats.Misses)
+	}
+}
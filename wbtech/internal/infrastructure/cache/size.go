@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"unsafe"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+)
+
+const entryOverhead = int64(unsafe.Sizeof(entry{})) + 64
+
+// estimateSize approximates the heap footprint of an order: the fixed size of
+// the structs plus the bytes referenced by every string field.
+func estimateSize(o *entities.Order) int64 {
+	size := entryOverhead + int64(unsafe.Sizeof(*o))
+
+	size += int64(len(o.OrderUID) + len(o.TrackNumber) + len(o.Entry) + len(o.Locale) +
+		len(o.InternalSignature) + len(o.CustomerID) + len(o.DeliveryService) +
+		len(o.ShardKey) + len(o.DateCreated) + len(o.OofShard))
+
+	d := o.Delivery
+	size += int64(len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) +
+		len(d.Address) + len(d.Region) + len(d.Email))
+
+	p := o.Payment
+	size += int64(len(p.Transaction) + len(p.RequestID) + len(p.Currency) +
+		len(p.Provider) + len(p.Bank))
+
+	size += int64(cap(o.Items)) * int64(unsafe.Sizeof(entities.Item{}))
+	for _, item := range o.Items {
+		size += int64(len(item.TrackNumber) + len(item.Rid) + len(item.Name) +
+			len(item.Size) + len(item.Brand))
+	}
+
+	size += int64(cap(o.Warnings)) * int64(unsafe.Sizeof(entities.Warning{}))
+	for _, w := range o.Warnings {
+		size += int64(len(w.Path) + len(w.Rule) + len(w.Message))
+	}
+
+	return size
+}
@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+const (
+	snapshotVersion = 1
+	// restoreChunkSize is how many snapshot orders are checked against the
+	// database at once.
+	restoreChunkSize = 1000
+)
+
+var ErrSnapshotCorrupted = errors.New("cache snapshot is corrupted")
+
+// SnapshotSource is the database the snapshot is reconciled with.
+type SnapshotSource interface {
+	// Watermark returns the point in time from which orders written after
+	// the snapshot can be found again by their updated_at.
+	Watermark(ctx context.Context) (time.Time, error)
+	// ExistingOrderUIDs returns which of uids are still stored.
+	ExistingOrderUIDs(ctx context.Context, uids []string) (map[string]bool, error)
+}
+
+type snapshotHeader struct {
+	Version   int       `json:"version"`
+	Watermark time.Time `json:"watermark"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type snapshotTrailer struct {
+	Count    int    `json:"count"`
+	Checksum string `json:"checksum"`
+}
+
+type Snapshotter struct {
+	cfg    config.Snapshot
+	cache  interfaces.OrderCache
+	source SnapshotSource
+}
+
+func NewSnapshotter(cfg config.Snapshot, cache interfaces.OrderCache, source SnapshotSource) *Snapshotter {
+	return &Snapshotter{
+		cfg:    cfg,
+		cache:  cache,
+		source: source,
+	}
+}
+
+func (s *Snapshotter) Enabled() bool {
+	return s.cfg.Path != ""
+}
+
+func (s *Snapshotter) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Save(ctx); err != nil {
+				logger.Log.Error("Failed to save cache snapshot", "path", s.cfg.Path, "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Save writes the snapshot next to its final location and renames it into
+// place, so a crash mid-write never leaves a truncated snapshot behind.
+func (s *Snapshotter) Save(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	start := time.Now()
+	watermark, err := s.source.Watermark(ctx)
+	if err != nil {
+		return fmt.Errorf("get watermark: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.cfg.Path), filepath.Base(s.cfg.Path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	count, err := s.write(tmp, watermark)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.cfg.Path); err != nil {
+		return err
+	}
+
+	logger.Log.Info("Cache snapshot saved", "path", s.cfg.Path, "count", count, "watermark", watermark, "elapsed", time.Since(start))
+	return nil
+}
+
+func (s *Snapshotter) write(w io.Writer, watermark time.Time) (int, error) {
+	gz := gzip.NewWriter(w)
+	buf := bufio.NewWriter(gz)
+
+	if err := writeLine(buf, snapshotHeader{
+		Version:   snapshotVersion,
+		Watermark: watermark,
+		CreatedAt: time.Now(),
+	}, nil); err != nil {
+		return 0, err
+	}
+
+	sum := sha256.New()
+	count := 0
+	var writeErr error
+	s.cache.Range(func(order *entities.Order) bool {
+		if writeErr = writeLine(buf, order, sum); writeErr != nil {
+			return false
+		}
+		count++
+		return true
+	})
+	if writeErr != nil {
+		return 0, writeErr
+	}
+
+	if err := writeLine(buf, snapshotTrailer{
+		Count:    count,
+		Checksum: hex.EncodeToString(sum.Sum(nil)),
+	}, nil); err != nil {
+		return 0, err
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func writeLine(w io.Writer, v any, sum hash.Hash) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if sum != nil {
+		sum.Write(b)
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Restore loads the snapshot orders that are still stored into the cache and
+// returns the point in time from which the database has to be reconciled. ok
+// is false when there is no usable snapshot and a full warm-up is required.
+func (s *Snapshotter) Restore(ctx context.Context) (since time.Time, ok bool) {
+	if !s.Enabled() {
+		return time.Time{}, false
+	}
+
+	start := time.Now()
+	orders, header, err := s.read()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Log.Info("No cache snapshot found", "path", s.cfg.Path)
+		} else {
+			logger.Log.Error("Failed to load cache snapshot", "path", s.cfg.Path, "error", err)
+		}
+		return time.Time{}, false
+	}
+
+	// Deleted orders leave nothing behind to find by updated_at, so every
+	// order in the snapshot is checked instead.
+	restored := 0
+	for chunk := range slices.Chunk(orders, restoreChunkSize) {
+		uids := make([]string, len(chunk))
+		for i, order := range chunk {
+			uids[i] = order.OrderUID
+		}
+		existing, err := s.source.ExistingOrderUIDs(ctx, uids)
+		if err != nil {
+			logger.Log.Error("Failed to check cache snapshot against the database", "path", s.cfg.Path, "error", err)
+			s.cache.Flush()
+			return time.Time{}, false
+		}
+		for _, order := range chunk {
+			if existing[order.OrderUID] {
+				s.cache.Set(order)
+				restored++
+			}
+		}
+	}
+
+	logger.Log.Info("Cache snapshot restored", "path", s.cfg.Path, "count", restored, "deleted", len(orders)-restored, "watermark", header.Watermark, "elapsed", time.Since(start))
+	return header.Watermark, true
+}
+
+func (s *Snapshotter) read() ([]*entities.Order, *snapshotHeader, error) {
+	f, err := os.Open(s.cfg.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupted, err)
+	}
+	defer gz.Close()
+
+	reader := bufio.NewReader(gz)
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: read header: %v", ErrSnapshotCorrupted, err)
+	}
+	var header snapshotHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, nil, fmt.Errorf("%w: decode header: %v", ErrSnapshotCorrupted, err)
+	}
+	if header.Version != snapshotVersion {
+		return nil, nil, fmt.Errorf("unsupported cache snapshot version %d", header.Version)
+	}
+
+	sum := sha256.New()
+	var orders []*entities.Order
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: missing trailer: %v", ErrSnapshotCorrupted, err)
+		}
+
+		var order entities.Order
+		if err := json.Unmarshal(line, &order); err != nil {
+			return nil, nil, fmt.Errorf("%w: decode order: %v", ErrSnapshotCorrupted, err)
+		}
+		if order.OrderUID == "" {
+			var trailer snapshotTrailer
+			if err := json.Unmarshal(line, &trailer); err != nil {
+				return nil, nil, fmt.Errorf("%w: decode trailer: %v", ErrSnapshotCorrupted, err)
+			}
+			if trailer.Count != len(orders) || trailer.Checksum != hex.EncodeToString(sum.Sum(nil)) {
+				return nil, nil, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupted)
+			}
+			return orders, &header, nil
+		}
+
+		sum.Write(line)
+		orders = append(orders, &order)
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"io"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/domain/entities"
+)
+
+// TieredCache keeps a short-lived local L1 in front of a shared L2. The L1 TTL
+// bounds how long a replica can serve an order that another replica changed.
+type TieredCache struct {
+	l1    interfaces.OrderCache
+	l2    interfaces.OrderCache
+	l1TTL time.Duration
+}
+
+func NewTieredCache(l1, l2 interfaces.OrderCache, l1TTL time.Duration) *TieredCache {
+	return &TieredCache{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+	}
+}
+
+func (c *TieredCache) Get(orderUID string) (*entities.Order, bool) {
+	if order, ok := c.l1.Get(orderUID); ok {
+		return order, true
+	}
+
+	order, ok := c.l2.Get(orderUID)
+	if !ok {
+		return nil, false
+	}
+	c.l1.SetWithTTL(order, c.l1TTL)
+
+	return order, true
+}
+
+func (c *TieredCache) Set(order *entities.Order) {
+	c.l2.Set(order)
+	c.l1.SetWithTTL(order, c.l1TTL)
+}
+
+func (c *TieredCache) SetWithTTL(order *entities.Order, ttl time.Duration) {
+	c.l2.SetWithTTL(order, ttl)
+	if ttl <= 0 || ttl > c.l1TTL {
+		ttl = c.l1TTL
+	}
+	c.l1.SetWithTTL(order, ttl)
+}
+
+func (c *TieredCache) Delete(orderUID string) {
+	c.l2.Delete(orderUID)
+	c.l1.Delete(orderUID)
+}
+
+func (c *TieredCache) Len() int {
+	return c.l2.Len()
+}
+
+func (c *TieredCache) Range(fn func(order *entities.Order) bool) {
+	c.l2.Range(fn)
+}
+
+func (c *TieredCache) Flush() {
+	c.l2.Flush()
+	c.l1.Flush()
+}
+
+// Stats reports the entries of the local L1 only; the L2 is not counted.
+func (c *TieredCache) Stats() dto.CacheStats {
+	l1 := c.l1.Stats()
+	l2 := c.l2.Stats()
+
+	stats := dto.CacheStats{
+		Backend: string(BackendTiered),
+		Hits:    l1.Hits + l2.Hits,
+		Misses:  l2.Misses,
+		Entries: l1.Entries,
+		Tiers:   []dto.CacheStats{l1, l2},
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+func (c *TieredCache) Close() error {
+	if closer, ok := c.l2.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
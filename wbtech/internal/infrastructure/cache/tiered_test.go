@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/alicebob/miniredis/v2"
+)
+
+const testL1TTL = 30 * time.Second
+
+type tieredTest struct {
+	cache   *TieredCache
+	l1      *MemoryCache
+	l2      *RedisCache
+	server  *miniredis.Miniredis
+	advance func(time.Duration)
+}
+
+func testTiered(t *testing.T) tieredTest {
+	t.Helper()
+
+	l1, advance := testCache(config.Cache{})
+	server, l2 := testRedis(t, time.Hour)
+	return tieredTest{
+		cache:   NewTieredCache(l1, l2, testL1TTL),
+		l1:      l1,
+		l2:      l2,
+		server:  server,
+		advance: advance,
+	}
+}
+
+func TestTieredCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        func(tc tieredTest)
+		want       bool
+		wantL1Hits uint64
+		wantL2Hits uint64
+	}{
+		{
+			name: "l1 hit does not reach l2",
+			run: func(tc tieredTest) {
+				tc.cache.Set(order("a"))
+				tc.server.Del(testPrefix + "a")
+			},
+			want:       true,
+			wantL1Hits: 1,
+		},
+		{
+			name: "l1 miss falls through to l2",
+			run: func(tc tieredTest) {
+				tc.l2.Set(order("a"))
+			},
+			want:       true,
+			wantL2Hits: 1,
+		},
+		{
+			name: "l2 hit refills l1",
+			run: func(tc tieredTest) {
+				tc.l2.Set(order("a"))
+				tc.cache.Get("a")
+				tc.server.Del(testPrefix + "a")
+			},
+			want:       true,
+			wantL1Hits: 1,
+		},
+		{
+			name: "expired l1 entry is read from l2 again",
+			run: func(tc tieredTest) {
+				tc.cache.Set(order("a"))
+				tc.advance(testL1TTL + time.Second)
+			},
+			want:       true,
+			wantL2Hits: 1,
+		},
+		{
+			name: "per-entry ttl is capped at the l1 ttl",
+			run: func(tc tieredTest) {
+				tc.cache.SetWithTTL(order("a"), time.Hour)
+				tc.server.Del(testPrefix + "a")
+				tc.advance(testL1TTL + time.Second)
+			},
+		},
+		{
+			name: "expired l2 entry is a miss",
+			run: func(tc tieredTest) {
+				tc.cache.SetWithTTL(order("a"), time.Second)
+				tc.advance(time.Minute)
+				tc.server.FastForward(time.Minute)
+			},
+		},
+		{
+			name: "delete removes both tiers",
+			run: func(tc tieredTest) {
+				tc.cache.Set(order("a"))
+				tc.cache.Delete("a")
+			},
+		},
+		{
+			name: "l1 keeps serving while redis is down",
+			run: func(tc tieredTest) {
+				tc.cache.Set(order("a"))
+				tc.server.Close()
+			},
+			want:       true,
+			wantL1Hits: 1,
+		},
+		{
+			name: "l1 miss while redis is down is a miss",
+			run: func(tc tieredTest) {
+				tc.cache.Set(order("a"))
+				tc.advance(testL1TTL + time.Second)
+				tc.server.Close()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testTiered(t)
+			tt.run(tc)
+			l1Before, l2Before := tc.l1.Stats().Hits, tc.l2.Stats().Hits
+
+			if _, ok := tc.cache.Get("a"); ok != tt.want {
+				t.Errorf("cached = %v, want %v", ok, tt.want)
+			}
+			if hits := tc.l1.Stats().Hits - l1Before; hits != tt.wantL1Hits {
+				t.Errorf("l1 hits = %d, want %d", hits, tt.wantL1Hits)
+			}
+			if hits := tc.l2.Stats().Hits - l2Before; hits != tt.wantL2Hits {
+				t.Errorf("l2 hits = %d, want %d", hits, tt.wantL2Hits)
+			}
+		})
+	}
+}
+
+func TestTieredCacheStats(t *testing.T) {
+	tc := testTiered(t)
+	tc.cache.Set(order("a"))
+	tc.l2.Set(order("b"))
+
+	before := tc.server.CommandCount()
+	stats := tc.cache.Stats()
+	if n := tc.server.CommandCount() - before; n != 0 {
+		t.Errorf("stats sent %d commands to redis", n)
+	}
+	if stats.Entries != 1 {
+		t.Errorf("entries = %d, want the 1 entry in l1", stats.Entries)
+	}
+	if len(stats.Tiers) != 2 {
+		t.Errorf("tiers = %d, want 2", len(stats.Tiers))
+	}
+}
@@ -0,0 +1,10 @@
+package consumers
+
+// Broker is the ingest.broker setting: where orders are consumed from.
+type Broker string
+
+const (
+	BrokerKafka  Broker = "kafka"
+	BrokerNATS   Broker = "nats"
+	BrokerMemory Broker = "memory"
+)
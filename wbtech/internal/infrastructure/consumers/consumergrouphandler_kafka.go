@@ -1,17 +1,19 @@
 package consumers
 
 import (
-	"encoding/json"
-	"errors"
-	"fmt"
+	"time"
 
 	"github.com/IBM/sarama"
-	"github.com/agl/wbtech/internal/domain/entities"
-	"github.com/agl/wbtech/pkg/logger"
+	"github.com/agl/wbtech/internal/application/interfaces"
 )
 
 type ConsumerGroupHandler struct {
-	msgChan chan<- []byte
+	msgChan      chan<- interfaces.Message
+	deadLetters  interfaces.DeadLetterPublisher
+	onNack       func(msg *sarama.ConsumerMessage, err error)
+	stopping     func() bool
+	drainTimeout time.Duration
+	applyPause   func(topic string, partition int32)
 }
 
 func (h *ConsumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error {
@@ -21,48 +23,59 @@ func (h *ConsumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error {
 	return nil
 }
 
+// ConsumeClaim hands messages over without marking them; the offset is marked
+// by the message's Ack once the order has been stored.
 func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for msg := range claim.Messages() {
-		logger.Log.Info("Received message", "value", string(msg.Value))
-		var event entities.Order
-		if err := json.Unmarshal(msg.Value, &event); err != nil {
-			logger.Log.Error("Error unmarshalling message", "error", err)
-			continue
-		}
-		if err := validateOrder(&event); err != nil {
-			logger.Log.Error("Order validation failed", "error", err, "order_uid", event.OrderUID)
-			continue
+	tracker := newOffsetTracker(claim.Topic(), claim.Partition(), func(offset int64) {
+		session.MarkOffset(claim.Topic(), claim.Partition(), offset, "")
+	})
+	msgCtx := processingContext(session.Context(), h.drainTimeout)
+
+	// Claims created after a rebalance or a restarted session start
+	// unpaused, so a pause in effect is applied to them again.
+	h.applyPause(claim.Topic(), claim.Partition())
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			tracker.track(msg.Offset)
+
+			orderUID, stage, err := decodeOrder(msg.Value, metadataOf(msg))
+			if err != nil {
+				h.deadLetter(msg, tracker, stage, err)
+				continue
+			}
+
+			select {
+			case h.msgChan <- &kafkaMessage{ctx: msgCtx, msg: msg, orderUID: orderUID, tracker: tracker, onNack: h.onNack}:
+			case <-session.Context().Done():
+				tracker.forget(msg.Offset)
+				h.drain(tracker)
+				return nil
+			}
+		case <-session.Context().Done():
+			h.drain(tracker)
+			return nil
 		}
-		logger.Log.Info("Message is correctly unmarshalled", "order_uid", event.OrderUID)
-		h.msgChan <- msg.Value
-		session.MarkMessage(msg, "")
 	}
-	return nil
 }
 
-func validateOrder(o *entities.Order) error {
-	if o.OrderUID == "" || o.TrackNumber == "" || o.Entry == "" ||
-		o.Locale == "" || o.CustomerID == "" || o.DeliveryService == "" ||
-		o.ShardKey == "" || o.SmID == 0 || o.DateCreated == "" || o.OofShard == "" {
-		return errors.New("missing required order fields")
-	}
-	if o.Delivery.Name == "" || o.Delivery.Phone == "" || o.Delivery.Zip == "" ||
-		o.Delivery.City == "" || o.Delivery.Address == "" || o.Delivery.Region == "" || o.Delivery.Email == "" {
-		return errors.New("missing required delivery fields")
+// drain holds the session open on shutdown until the messages already handed
+// over are acknowledged, so their offsets are part of the final commit.
+func (h *ConsumerGroupHandler) drain(tracker *offsetTracker) {
+	if !h.stopping() {
+		return
 	}
-	if o.Payment.Transaction == "" || o.Payment.Currency == "" || o.Payment.Provider == "" ||
-		o.Payment.Amount == 0 || o.Payment.PaymentDT == 0 || o.Payment.Bank == "" {
-		return errors.New("missing required payment fields")
-	}
-	if len(o.Items) == 0 {
-		return errors.New("items is empty")
-	}
-	for i, item := range o.Items {
-		if item.ChrtID == 0 || item.TrackNumber == "" || item.Price == 0 ||
-			item.Rid == "" || item.Name == "" || item.Size == "" ||
-			item.TotalPrice == 0 || item.NmID == 0 || item.Brand == "" || item.Status == 0 {
-			return fmt.Errorf("missing required item fields in item %d", i)
-		}
+	tracker.drain(h.drainTimeout)
+}
+
+func (h *ConsumerGroupHandler) deadLetter(msg *sarama.ConsumerMessage, tracker *offsetTracker, stage string, cause error) {
+	if err := h.deadLetters.PublishDeadLetter(msg.Value, metadataOf(msg), stage, cause); err != nil {
+		h.onNack(msg, err)
+		return
 	}
-	return nil
+	tracker.ack(msg.Offset)
 }
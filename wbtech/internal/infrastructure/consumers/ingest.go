@@ -0,0 +1,46 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/logger"
+	"github.com/agl/wbtech/pkg/validation"
+)
+
+const consumeRetryDelay = time.Second
+
+// decodeOrder checks a message before it is handed over and returns the order
+// uid, or the dead-letter stage it failed at. Every broker implementation uses
+// it, so they reject the same messages.
+//
+// Orders carry customer details, so only the position of the message is
+// logged, never its payload.
+func decodeOrder(value []byte, md dto.MessageMetadata) (string, string, error) {
+	var event entities.Order
+	if err := json.Unmarshal(value, &event); err != nil {
+		logger.Log.Error("Error unmarshalling message", "topic", md.Topic, "partition", md.Partition, "offset", md.Offset, "error", err)
+		return "", interfaces.StageDecode, err
+	}
+	if err := validation.ValidateOrder(value); err != nil {
+		logger.Log.Error("Order validation failed", "order_uid", event.OrderUID, "topic", md.Topic, "partition", md.Partition, "offset", md.Offset, "error", err)
+		return "", interfaces.StageValidate, err
+	}
+	logger.Log.Debug("Received order", "order_uid", event.OrderUID, "topic", md.Topic, "partition", md.Partition, "offset", md.Offset)
+
+	return event.OrderUID, "", nil
+}
+
+// processingContext outlives the session by the drain timeout, so orders handed
+// over just before shutdown or a rebalance can still be written to the database.
+func processingContext(session context.Context, grace time.Duration) context.Context {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(session))
+	context.AfterFunc(session, func() {
+		time.AfterFunc(grace, cancel)
+	})
+	return ctx
+}
@@ -0,0 +1,49 @@
+package consumers
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+func TestDecodeOrder(t *testing.T) {
+	invalid := bytes.Replace(validOrder("b"), []byte(`"test@gmail.com"`), []byte(`"test at gmail"`), 1)
+
+	tests := []struct {
+		name      string
+		value     []byte
+		wantUID   string
+		wantStage string
+	}{
+		{name: "valid order", value: validOrder("a"), wantUID: "a"},
+		{name: "not json", value: []byte(`{"order_uid": "a", "delivery": {"email": "test@gmail.com"`), wantStage: interfaces.StageDecode},
+		{name: "breaks the schema", value: invalid, wantStage: interfaces.StageValidate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			saved := logger.Log
+			logger.Log = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			defer func() { logger.Log = saved }()
+
+			md := dto.MessageMetadata{Topic: testTopic, Partition: 2, Offset: 42}
+			uid, stage, err := decodeOrder(tt.value, md)
+
+			if uid != tt.wantUID || stage != tt.wantStage || (err != nil) != (tt.wantStage != "") {
+				t.Errorf("decodeOrder = %q, %q, %v; want %q, %q", uid, stage, err, tt.wantUID, tt.wantStage)
+			}
+			if out := logs.String(); strings.Contains(out, "gmail") || strings.Contains(out, "Test Testov") {
+				t.Errorf("payload logged: %s", out)
+			}
+			if out := logs.String(); !strings.Contains(out, "offset=42") {
+				t.Errorf("message position not logged: %s", out)
+			}
+		})
+	}
+}
@@ -2,43 +2,126 @@ package consumers
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
 	"github.com/agl/wbtech/pkg/logger"
 )
 
-const topic = "service.message"
-
 type KafkaConsumer struct {
-	Kafka sarama.ConsumerGroup
-}
+	Kafka        sarama.ConsumerGroup
+	topics       []string
+	deadLetters  interfaces.DeadLetterPublisher
+	drainTimeout time.Duration
 
-func NewKafkaConsumer(brokers []string, groupID string) *KafkaConsumer {
-	config := sarama.NewConfig()
-	config.Consumer.Return.Errors = true
-	config.Version = sarama.V2_1_0_0
+	mu      sync.Mutex
+	restart context.CancelFunc
+	pauses  pauseState
+}
 
-	consumerGroup, err := sarama.NewConsumerGroup(brokers, groupID, config)
+func NewKafkaConsumer(cfg kafka.Config, drainTimeout time.Duration, deadLetters interfaces.DeadLetterPublisher) *KafkaConsumer {
+	consumerGroup, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupID, cfg.ConsumerGroup())
 	if err != nil {
 		logger.Log.Error("Couldn't create kafka consumer group", "error", err)
 		return nil
 	}
 
-	logger.Log.Info("Kafka consumer group created successfully")
+	logger.Log.Info("Kafka consumer group created successfully", "group_id", cfg.GroupID, "topics", cfg.Topics)
 
 	return &KafkaConsumer{
-		Kafka: consumerGroup,
+		Kafka:        consumerGroup,
+		topics:       cfg.Topics,
+		deadLetters:  deadLetters,
+		drainTimeout: drainTimeout,
 	}
 }
 
-func (kc *KafkaConsumer) Consume(msgChan chan<- []byte) {
-	handler := &ConsumerGroupHandler{msgChan: msgChan}
-	ctx := context.Background()
+// Consume fetches messages until ctx is cancelled. On cancellation every claim
+// stops fetching and waits for its in-flight messages to be acknowledged, so
+// their offsets are committed when the session ends; msgChan is closed after
+// that.
+func (kc *KafkaConsumer) Consume(ctx context.Context, msgChan chan<- interfaces.Message) {
+	handler := &ConsumerGroupHandler{
+		msgChan:      msgChan,
+		deadLetters:  kc.deadLetters,
+		onNack:       kc.nack,
+		stopping:     func() bool { return ctx.Err() != nil },
+		drainTimeout: kc.drainTimeout,
+		applyPause:   kc.applyPause,
+	}
 	go func() {
-		for {
-			if err := kc.Kafka.Consume(ctx, []string{topic}, handler); err != nil {
+		defer close(msgChan)
+		for ctx.Err() == nil {
+			sessionCtx, cancel := context.WithCancel(ctx)
+			kc.mu.Lock()
+			kc.restart = cancel
+			kc.mu.Unlock()
+
+			err := kc.Kafka.Consume(sessionCtx, kc.topics, handler)
+			cancel()
+			if err != nil {
 				logger.Log.Error("Error from consumer group", "error", err)
+				select {
+				case <-time.After(consumeRetryDelay):
+				case <-ctx.Done():
+				}
 			}
 		}
+		logger.Log.Info("Kafka consumer stopped fetching messages")
 	}()
 }
+
+func (kc *KafkaConsumer) Close() error {
+	return kc.Kafka.Close()
+}
+
+// nack ends the current session so that consumption resumes from the last
+// committed offsets and the unacknowledged message is delivered again.
+func (kc *KafkaConsumer) nack(msg *sarama.ConsumerMessage, err error) {
+	logger.Log.Warn("Message not acknowledged, restarting consumer session", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "error", err)
+
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	if kc.restart != nil {
+		kc.restart()
+	}
+}
+
+// Pause stops fetching from every claimed partition while keeping the group
+// membership, so no rebalance is triggered. Messages already fetched are
+// still delivered.
+func (kc *KafkaConsumer) Pause(reason string) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+
+	if kc.pauses.pause(reason) {
+		kc.Kafka.PauseAll()
+	}
+}
+
+// Resume clears reason and resumes fetching once no other reason is left.
+func (kc *KafkaConsumer) Resume(reason string) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+
+	if kc.pauses.resume(reason) {
+		kc.Kafka.ResumeAll()
+	}
+}
+
+func (kc *KafkaConsumer) PauseState() dto.PauseState {
+	return kc.pauses.state()
+}
+
+func (kc *KafkaConsumer) applyPause(topic string, partition int32) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+
+	if kc.pauses.paused() {
+		kc.Kafka.Pause(map[string][]int32{topic: {partition}})
+	}
+}
@@ -0,0 +1,167 @@
+package consumers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/infrastructure/memory"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+// MemoryConsumer reads from an in-process broker with the semantics of the
+// Kafka consumer: the committed offset only covers the acknowledged prefix, a
+// nack restarts delivery from the committed offset and shutdown waits for
+// in-flight messages.
+type MemoryConsumer struct {
+	broker       *memory.Broker
+	topics       []string
+	deadLetters  interfaces.DeadLetterPublisher
+	drainTimeout time.Duration
+
+	mu       sync.Mutex
+	restarts map[string]context.CancelFunc
+	pauses   pauseState
+}
+
+func NewMemoryConsumer(broker *memory.Broker, topics []string, drainTimeout time.Duration, deadLetters interfaces.DeadLetterPublisher) *MemoryConsumer {
+	return &MemoryConsumer{
+		broker:       broker,
+		topics:       topics,
+		deadLetters:  deadLetters,
+		drainTimeout: drainTimeout,
+		restarts:     make(map[string]context.CancelFunc),
+	}
+}
+
+func (mc *MemoryConsumer) Consume(ctx context.Context, msgChan chan<- interfaces.Message) {
+	go func() {
+		defer close(msgChan)
+
+		var wg sync.WaitGroup
+		for _, topic := range mc.topics {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ctx.Err() == nil {
+					sessionCtx, cancel := context.WithCancel(ctx)
+					mc.mu.Lock()
+					mc.restarts[topic] = cancel
+					mc.mu.Unlock()
+
+					mc.session(sessionCtx, topic, msgChan, func() bool { return ctx.Err() != nil })
+					cancel()
+				}
+			}()
+		}
+		wg.Wait()
+		logger.Log.Info("In-memory consumer stopped fetching messages")
+	}()
+}
+
+// session delivers topic from its committed offset until ctx is cancelled.
+func (mc *MemoryConsumer) session(ctx context.Context, topic string, msgChan chan<- interfaces.Message, stopping func() bool) {
+	tracker := newOffsetTracker(topic, 0, func(next int64) {
+		mc.broker.Commit(topic, next)
+	})
+	msgCtx := processingContext(ctx, mc.drainTimeout)
+	defer func() {
+		if stopping() {
+			tracker.drain(mc.drainTimeout)
+		}
+	}()
+
+	for offset := mc.broker.Committed(topic); ; offset++ {
+		rec, err := mc.broker.Fetch(ctx, topic, offset)
+		// A pause also holds back a record that arrived while waiting.
+		if err != nil || !mc.pauses.wait(ctx) {
+			return
+		}
+		tracker.track(rec.Offset)
+
+		md := dto.MessageMetadata{
+			Topic:     topic,
+			Offset:    rec.Offset,
+			Key:       rec.Key,
+			Timestamp: rec.Timestamp,
+		}
+		orderUID, stage, err := decodeOrder(rec.Value, md)
+		if err != nil {
+			if err := mc.deadLetters.PublishDeadLetter(rec.Value, md, stage, err); err != nil {
+				mc.nack(md, err)
+				continue
+			}
+			tracker.ack(rec.Offset)
+			continue
+		}
+		md.OrderUID = orderUID
+
+		select {
+		case msgChan <- &memoryMessage{ctx: msgCtx, value: rec.Value, md: md, tracker: tracker, onNack: mc.nack}:
+		case <-ctx.Done():
+			tracker.forget(rec.Offset)
+			return
+		}
+	}
+}
+
+func (mc *MemoryConsumer) Close() error {
+	return nil
+}
+
+func (mc *MemoryConsumer) nack(md dto.MessageMetadata, err error) {
+	logger.Log.Warn("Message not acknowledged, restarting delivery", "topic", md.Topic, "offset", md.Offset, "error", err)
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if restart := mc.restarts[md.Topic]; restart != nil {
+		restart()
+	}
+}
+
+func (mc *MemoryConsumer) Pause(reason string) {
+	mc.pauses.pause(reason)
+}
+
+func (mc *MemoryConsumer) Resume(reason string) {
+	mc.pauses.resume(reason)
+}
+
+func (mc *MemoryConsumer) PauseState() dto.PauseState {
+	return mc.pauses.state()
+}
+
+type memoryMessage struct {
+	ctx     context.Context
+	value   []byte
+	md      dto.MessageMetadata
+	tracker *offsetTracker
+	onNack  func(md dto.MessageMetadata, err error)
+	once    sync.Once
+}
+
+func (m *memoryMessage) Context() context.Context {
+	return m.ctx
+}
+
+func (m *memoryMessage) Value() []byte {
+	return m.value
+}
+
+func (m *memoryMessage) Metadata() dto.MessageMetadata {
+	return m.md
+}
+
+func (m *memoryMessage) Ack() {
+	m.once.Do(func() {
+		m.tracker.ack(m.md.Offset)
+	})
+}
+
+func (m *memoryMessage) Nack(err error) {
+	m.once.Do(func() {
+		m.onNack(m.md, err)
+	})
+}
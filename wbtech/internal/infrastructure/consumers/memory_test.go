@@ -0,0 +1,170 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/infrastructure/memory"
+)
+
+const testTopic = "orders"
+
+func validOrder(uid string) []byte {
+	return fmt.Appendf(nil, `{
+		"order_uid": %q, "track_number": "WBILMTESTTRACK", "entry": "WBIL", "locale": "en",
+		"customer_id": "test", "delivery_service": "meest", "shardkey": "9", "sm_id": 99,
+		"date_created": "2021-11-26T06:22:19Z", "oof_shard": "1",
+		"delivery": {"name": "Test Testov", "phone": "+9720000000", "zip": "2639809", "city": "Kiryat Mozkin",
+			"address": "Ploshad Mira 15", "region": "Kraiot", "email": "test@gmail.com"},
+		"payment": {"transaction": %q, "currency": "USD", "provider": "wbpay", "amount": 1817,
+			"payment_dt": 1637907727, "bank": "alpha", "delivery_cost": 1500, "goods_total": 317, "custom_fee": 0},
+		"items": [{"chrt_id": 9934930, "track_number": "WBILMTESTTRACK", "price": 453, "rid": "ab4219087a764ae0btest",
+			"name": "Mascaras", "sale": 30, "size": "0", "total_price": 317, "nm_id": 2389212, "brand": "Vivienne Sabo", "status": 202}]
+	}`, uid, uid)
+}
+
+type recordedDeadLetter struct {
+	md    dto.MessageMetadata
+	stage string
+}
+
+type fakeDeadLetters struct {
+	mu      sync.Mutex
+	letters []recordedDeadLetter
+}
+
+func (d *fakeDeadLetters) PublishDeadLetter(value []byte, source dto.MessageMetadata, stage string, cause error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.letters = append(d.letters, recordedDeadLetter{md: source, stage: stage})
+	return nil
+}
+
+func (d *fakeDeadLetters) list() []recordedDeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]recordedDeadLetter(nil), d.letters...)
+}
+
+func startMemoryConsumer(t *testing.T, broker *memory.Broker) (<-chan interfaces.Message, *fakeDeadLetters) {
+	t.Helper()
+
+	deadLetters := &fakeDeadLetters{}
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs := make(chan interfaces.Message)
+	NewMemoryConsumer(broker, []string{testTopic}, time.Second, deadLetters).Consume(ctx, msgs)
+	t.Cleanup(func() {
+		cancel()
+		for range msgs {
+		}
+	})
+	return msgs, deadLetters
+}
+
+func receive(t *testing.T, msgs <-chan interfaces.Message) interfaces.Message {
+	t.Helper()
+
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message delivered")
+		return nil
+	}
+}
+
+func waitCommitted(t *testing.T, broker *memory.Broker, want int64) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for broker.Committed(testTopic) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("committed = %d, want %d", broker.Committed(testTopic), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMemoryConsumerDeliversAndCommits(t *testing.T) {
+	broker := memory.NewBroker()
+	broker.Produce(testTopic, "a", validOrder("a"), nil)
+	broker.Produce(testTopic, "b", validOrder("b"), nil)
+	msgs, _ := startMemoryConsumer(t, broker)
+
+	a := receive(t, msgs)
+	b := receive(t, msgs)
+	if md := a.Metadata(); md.Topic != testTopic || md.Offset != 0 || md.Key != "a" || md.OrderUID != "a" {
+		t.Errorf("metadata = %+v", md)
+	}
+	if b.Metadata().OrderUID != "b" {
+		t.Errorf("second order = %q, want b", b.Metadata().OrderUID)
+	}
+
+	// Only the acknowledged prefix is committed.
+	b.Ack()
+	time.Sleep(10 * time.Millisecond)
+	if got := broker.Committed(testTopic); got != 0 {
+		t.Errorf("committed = %d before the first message was acked", got)
+	}
+	a.Ack()
+	waitCommitted(t, broker, 2)
+
+	// Orders produced later are delivered as well.
+	broker.Produce(testTopic, "c", validOrder("c"), nil)
+	c := receive(t, msgs)
+	if c.Metadata().OrderUID != "c" {
+		t.Errorf("order = %q, want c", c.Metadata().OrderUID)
+	}
+	c.Ack()
+	waitCommitted(t, broker, 3)
+}
+
+func TestMemoryConsumerNackRedelivers(t *testing.T) {
+	broker := memory.NewBroker()
+	broker.Produce(testTopic, "a", validOrder("a"), nil)
+	broker.Produce(testTopic, "b", validOrder("b"), nil)
+	msgs, _ := startMemoryConsumer(t, broker)
+
+	receive(t, msgs).Ack()
+	waitCommitted(t, broker, 1)
+	receive(t, msgs).Nack(fmt.Errorf("database unavailable"))
+
+	msg := receive(t, msgs)
+	if md := msg.Metadata(); md.Offset != 1 || md.OrderUID != "b" {
+		t.Errorf("redelivered %+v, want offset 1 of b", md)
+	}
+	msg.Ack()
+	waitCommitted(t, broker, 2)
+}
+
+func TestMemoryConsumerDeadLettersInvalidMessages(t *testing.T) {
+	broker := memory.NewBroker()
+	broker.Produce(testTopic, "", []byte(`not json`), nil)
+	broker.Produce(testTopic, "", []byte(`{"order_uid": "x"}`), nil)
+	broker.Produce(testTopic, "a", validOrder("a"), nil)
+	msgs, deadLetters := startMemoryConsumer(t, broker)
+
+	msg := receive(t, msgs)
+	if msg.Metadata().OrderUID != "a" {
+		t.Fatalf("delivered %q, want only the valid order", msg.Metadata().OrderUID)
+	}
+	msg.Ack()
+	waitCommitted(t, broker, 3)
+
+	letters := deadLetters.list()
+	if len(letters) != 2 {
+		t.Fatalf("dead letters = %d, want 2", len(letters))
+	}
+	for i, want := range []string{interfaces.StageDecode, interfaces.StageValidate} {
+		if letters[i].stage != want || letters[i].md.Offset != int64(i) {
+			t.Errorf("dead letter %d = offset %d stage %q, want offset %d stage %q", i, letters[i].md.Offset, letters[i].stage, i, want)
+		}
+	}
+}
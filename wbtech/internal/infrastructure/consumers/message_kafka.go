@@ -0,0 +1,54 @@
+package consumers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/application/dto"
+)
+
+type kafkaMessage struct {
+	ctx      context.Context
+	msg      *sarama.ConsumerMessage
+	orderUID string
+	tracker  *offsetTracker
+	onNack   func(msg *sarama.ConsumerMessage, err error)
+	once     sync.Once
+}
+
+func (m *kafkaMessage) Context() context.Context {
+	return m.ctx
+}
+
+func (m *kafkaMessage) Value() []byte {
+	return m.msg.Value
+}
+
+func (m *kafkaMessage) Metadata() dto.MessageMetadata {
+	md := metadataOf(m.msg)
+	md.OrderUID = m.orderUID
+	return md
+}
+
+func (m *kafkaMessage) Ack() {
+	m.once.Do(func() {
+		m.tracker.ack(m.msg.Offset)
+	})
+}
+
+func (m *kafkaMessage) Nack(err error) {
+	m.once.Do(func() {
+		m.onNack(m.msg, err)
+	})
+}
+
+func metadataOf(msg *sarama.ConsumerMessage) dto.MessageMetadata {
+	return dto.MessageMetadata{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       string(msg.Key),
+		Timestamp: msg.Timestamp,
+	}
+}
@@ -0,0 +1,237 @@
+package consumers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/infrastructure/nats"
+	"github.com/agl/wbtech/pkg/logger"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const natsSetupTimeout = 10 * time.Second
+
+// NATSConsumer pulls from a durable JetStream consumer with explicit acks. A
+// nacked message is redelivered by JetStream after the nak delay; unlike
+// Kafka, the messages after it are not delivered again. Messages that are
+// neither acked nor nacked are redelivered once the ack wait expires, so the
+// deadline is extended while a message waits for a worker or is retried.
+type NATSConsumer struct {
+	cfg          nats.Config
+	consumer     jetstream.Consumer
+	deadLetters  interfaces.DeadLetterPublisher
+	drainTimeout time.Duration
+
+	inFlight sync.WaitGroup
+	pauses   pauseState
+}
+
+func NewNATSConsumer(cfg nats.Config, conn *natsgo.Conn, drainTimeout time.Duration, deadLetters interfaces.DeadLetterPublisher) (*NATSConsumer, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+		Durable:        cfg.Durable,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        cfg.AckWait,
+		MaxAckPending:  cfg.MaxAckPending,
+		FilterSubjects: cfg.Subjects,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("NATS consumer created successfully", "stream", cfg.Stream, "durable", cfg.Durable, "subjects", cfg.Subjects)
+
+	return &NATSConsumer{
+		cfg:          cfg,
+		consumer:     consumer,
+		deadLetters:  deadLetters,
+		drainTimeout: drainTimeout,
+	}, nil
+}
+
+// Consume fetches messages until ctx is cancelled, then waits for the messages
+// already handed over to be acknowledged before closing msgChan.
+func (nc *NATSConsumer) Consume(ctx context.Context, msgChan chan<- interfaces.Message) {
+	go func() {
+		defer close(msgChan)
+
+		msgCtx := processingContext(ctx, nc.drainTimeout)
+		for nc.pauses.wait(ctx) {
+			batch, err := nc.consumer.Fetch(nc.cfg.FetchBatch, jetstream.FetchMaxWait(nc.cfg.FetchMaxWait))
+			if err != nil {
+				logger.Log.Error("Error fetching from NATS", "error", err)
+				select {
+				case <-time.After(consumeRetryDelay):
+				case <-ctx.Done():
+				}
+				continue
+			}
+			for msg := range batch.Messages() {
+				nc.deliver(ctx, msgCtx, msg, msgChan)
+			}
+			if err := batch.Error(); err != nil {
+				logger.Log.Error("Error from NATS fetch", "error", err)
+			}
+		}
+
+		nc.drain()
+		logger.Log.Info("NATS consumer stopped fetching messages")
+	}()
+}
+
+func (nc *NATSConsumer) deliver(ctx, msgCtx context.Context, msg jetstream.Msg, msgChan chan<- interfaces.Message) {
+	// Messages of a batch fetched just before shutdown go back to the stream
+	// straight away rather than after the ack wait.
+	if ctx.Err() != nil {
+		if err := msg.Nak(); err != nil {
+			logger.Log.Warn("Failed to return message to NATS", "subject", msg.Subject(), "error", err)
+		}
+		return
+	}
+
+	md := natsMetadata(msg)
+	orderUID, stage, err := decodeOrder(msg.Data(), md)
+	if err != nil {
+		if err := nc.deadLetters.PublishDeadLetter(msg.Data(), md, stage, err); err != nil {
+			nc.nak(msg, md, err)
+			return
+		}
+		if err := msg.Ack(); err != nil {
+			logger.Log.Error("Failed to ack NATS message", "subject", md.Topic, "sequence", md.Offset, "error", err)
+		}
+		return
+	}
+	md.OrderUID = orderUID
+
+	nc.inFlight.Add(1)
+	m := &natsMessage{ctx: msgCtx, msg: msg, md: md, consumer: nc, done: make(chan struct{})}
+	go m.keepAlive(nc.cfg.AckWait / 2)
+	select {
+	case msgChan <- m:
+	case <-ctx.Done():
+		m.Nack(ctx.Err())
+	}
+}
+
+func (nc *NATSConsumer) nak(msg jetstream.Msg, md dto.MessageMetadata, cause error) {
+	logger.Log.Warn("Message not acknowledged, redelivering", "subject", md.Topic, "sequence", md.Offset, "delay", nc.cfg.NakDelay, "error", cause)
+	if err := msg.NakWithDelay(nc.cfg.NakDelay); err != nil {
+		logger.Log.Error("Failed to nak NATS message", "subject", md.Topic, "sequence", md.Offset, "error", err)
+	}
+}
+
+// drain waits for the messages handed over to be acknowledged, so shutdown
+// does not leave them to be redelivered after the ack wait.
+func (nc *NATSConsumer) drain() {
+	done := make(chan struct{})
+	go func() {
+		nc.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(nc.drainTimeout):
+		logger.Log.Warn("Timed out draining in-flight messages", "stream", nc.cfg.Stream)
+	}
+}
+
+// Close leaves the connection open; it is owned by the caller.
+func (nc *NATSConsumer) Close() error {
+	return nil
+}
+
+func (nc *NATSConsumer) Pause(reason string) {
+	nc.pauses.pause(reason)
+}
+
+func (nc *NATSConsumer) Resume(reason string) {
+	nc.pauses.resume(reason)
+}
+
+func (nc *NATSConsumer) PauseState() dto.PauseState {
+	return nc.pauses.state()
+}
+
+func natsMetadata(msg jetstream.Msg) dto.MessageMetadata {
+	md := dto.MessageMetadata{
+		Topic: msg.Subject(),
+		Key:   msg.Headers().Get(nats.HeaderKey),
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		md.Offset = int64(meta.Sequence.Stream)
+		md.Timestamp = meta.Timestamp
+	}
+	return md
+}
+
+type natsMessage struct {
+	ctx      context.Context
+	msg      jetstream.Msg
+	md       dto.MessageMetadata
+	consumer *NATSConsumer
+	once     sync.Once
+	done     chan struct{}
+}
+
+// keepAlive resets the ack wait every interval until the message is acked or
+// nacked. Processing is still bounded: the handler gives up after its retry
+// attempts, and msgCtx is cancelled once the drain timeout has passed.
+func (m *natsMessage) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.msg.InProgress(); err != nil {
+				logger.Log.Warn("Failed to extend NATS ack wait", "subject", m.md.Topic, "sequence", m.md.Offset, "error", err)
+			}
+		case <-m.done:
+			return
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *natsMessage) Context() context.Context {
+	return m.ctx
+}
+
+func (m *natsMessage) Value() []byte {
+	return m.msg.Data()
+}
+
+func (m *natsMessage) Metadata() dto.MessageMetadata {
+	return m.md
+}
+
+func (m *natsMessage) Ack() {
+	m.once.Do(func() {
+		defer m.consumer.inFlight.Done()
+		close(m.done)
+		if err := m.msg.Ack(); err != nil {
+			logger.Log.Error("Failed to ack NATS message", "subject", m.md.Topic, "sequence", m.md.Offset, "error", err)
+		}
+	})
+}
+
+func (m *natsMessage) Nack(err error) {
+	m.once.Do(func() {
+		defer m.consumer.inFlight.Done()
+		close(m.done)
+		m.consumer.nak(m.msg, m.md, err)
+	})
+}
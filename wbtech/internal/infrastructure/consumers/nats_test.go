@@ -0,0 +1,69 @@
+package consumers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type fakeNATSMsg struct {
+	jetstream.Msg
+	inProgress atomic.Int32
+	acked      atomic.Bool
+}
+
+func (m *fakeNATSMsg) InProgress() error {
+	m.inProgress.Add(1)
+	return nil
+}
+
+func (m *fakeNATSMsg) Ack() error {
+	m.acked.Store(true)
+	return nil
+}
+
+func TestNATSMessageKeepAlive(t *testing.T) {
+	msg := &fakeNATSMsg{}
+	nc := &NATSConsumer{}
+	nc.inFlight.Add(1)
+	m := &natsMessage{ctx: context.Background(), msg: msg, consumer: nc, done: make(chan struct{})}
+
+	go m.keepAlive(5 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if n := msg.inProgress.Load(); n < 2 {
+		t.Fatalf("ack wait extended %d times while processing, want at least 2", n)
+	}
+
+	m.Ack()
+	time.Sleep(10 * time.Millisecond)
+	after := msg.inProgress.Load()
+	time.Sleep(30 * time.Millisecond)
+	if n := msg.inProgress.Load(); n != after {
+		t.Errorf("ack wait extended %d more times after the ack", n-after)
+	}
+	if !msg.acked.Load() {
+		t.Error("message not acked")
+	}
+}
+
+func TestNATSMessageKeepAliveStopsWithContext(t *testing.T) {
+	msg := &fakeNATSMsg{}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &natsMessage{ctx: ctx, msg: msg, consumer: &NATSConsumer{}, done: make(chan struct{})}
+
+	stopped := make(chan struct{})
+	go func() {
+		m.keepAlive(time.Hour)
+		close(stopped)
+	}()
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("keepAlive still running after the message context ended")
+	}
+}
@@ -0,0 +1,90 @@
+package consumers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+const drainPollInterval = 50 * time.Millisecond
+
+// offsetTracker marks a partition offset only once every message received
+// before it has been acknowledged, so the committed offset never skips over
+// an order that is still being stored.
+type offsetTracker struct {
+	mu        sync.Mutex
+	mark      func(next int64)
+	topic     string
+	partition int32
+	received  []int64
+	acked     map[int64]bool
+}
+
+// mark is called with the offset to resume from, i.e. one past the last
+// acknowledged message.
+func newOffsetTracker(topic string, partition int32, mark func(next int64)) *offsetTracker {
+	return &offsetTracker{
+		mark:      mark,
+		topic:     topic,
+		partition: partition,
+		acked:     make(map[int64]bool),
+	}
+}
+
+func (t *offsetTracker) track(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.received = append(t.received, offset)
+}
+
+func (t *offsetTracker) ack(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.acked[offset] = true
+
+	mark := int64(-1)
+	for len(t.received) > 0 && t.acked[t.received[0]] {
+		mark = t.received[0]
+		delete(t.acked, mark)
+		t.received = t.received[1:]
+	}
+	if mark >= 0 {
+		t.mark(mark + 1)
+	}
+}
+
+func (t *offsetTracker) forget(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n := len(t.received); n > 0 && t.received[n-1] == offset {
+		t.received = t.received[:n-1]
+	}
+}
+
+func (t *offsetTracker) pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.received)
+}
+
+func (t *offsetTracker) waitDrained(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for t.pending() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(drainPollInterval)
+	}
+	return true
+}
+
+func (t *offsetTracker) drain(timeout time.Duration) {
+	if !t.waitDrained(timeout) {
+		logger.Log.Warn("Timed out draining in-flight messages", "topic", t.topic, "partition", t.partition, "pending", t.pending())
+	}
+}
@@ -0,0 +1,106 @@
+package consumers
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOffsetTrackerMarksContiguousPrefix(t *testing.T) {
+	tests := []struct {
+		name      string
+		received  []int64
+		acks      []int64
+		wantMarks []int64
+		pending   int
+	}{
+		{
+			name:      "in-order acks mark each offset",
+			received:  []int64{10, 11, 12},
+			acks:      []int64{10, 11, 12},
+			wantMarks: []int64{11, 12, 13},
+		},
+		{
+			name:      "later ack waits for the earlier one",
+			received:  []int64{10, 11, 12},
+			acks:      []int64{12, 11},
+			wantMarks: nil,
+			pending:   3,
+		},
+		{
+			name:      "filling the gap marks past every acked offset",
+			received:  []int64{10, 11, 12, 13},
+			acks:      []int64{12, 11, 10},
+			wantMarks: []int64{13},
+			pending:   1,
+		},
+		{
+			name:      "interleaved acks advance step by step",
+			received:  []int64{10, 11, 12, 13, 14},
+			acks:      []int64{11, 10, 13, 14, 12},
+			wantMarks: []int64{12, 15},
+		},
+		{
+			name:      "gaps in the partition offsets are skipped",
+			received:  []int64{10, 15, 20},
+			acks:      []int64{20, 10, 15},
+			wantMarks: []int64{11, 21},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var marks []int64
+			tracker := newOffsetTracker("orders", 0, func(next int64) { marks = append(marks, next) })
+			for _, offset := range tt.received {
+				tracker.track(offset)
+			}
+			for _, offset := range tt.acks {
+				tracker.ack(offset)
+			}
+
+			if !slices.Equal(marks, tt.wantMarks) {
+				t.Errorf("marks = %v, want %v", marks, tt.wantMarks)
+			}
+			if n := tracker.pending(); n != tt.pending {
+				t.Errorf("pending = %d, want %d", n, tt.pending)
+			}
+		})
+	}
+}
+
+func TestOffsetTrackerForget(t *testing.T) {
+	var marks []int64
+	tracker := newOffsetTracker("orders", 0, func(next int64) { marks = append(marks, next) })
+	tracker.track(10)
+	tracker.track(11)
+
+	// A message that was never handed over is forgotten, so it does not
+	// hold back the offsets before it.
+	tracker.forget(11)
+	tracker.ack(10)
+
+	if !slices.Equal(marks, []int64{11}) {
+		t.Errorf("marks = %v, want [11]", marks)
+	}
+	if n := tracker.pending(); n != 0 {
+		t.Errorf("pending = %d, want 0", n)
+	}
+}
+
+func TestOffsetTrackerWaitDrained(t *testing.T) {
+	tracker := newOffsetTracker("orders", 0, func(int64) {})
+	tracker.track(10)
+
+	if tracker.waitDrained(10 * time.Millisecond) {
+		t.Fatal("drained with an unacknowledged message")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tracker.ack(10)
+	}()
+	if !tracker.waitDrained(time.Second) {
+		t.Error("not drained after the last ack")
+	}
+}
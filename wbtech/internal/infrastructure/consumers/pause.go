@@ -0,0 +1,95 @@
+package consumers
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+const pausePollInterval = 200 * time.Millisecond
+
+// pauseState keeps the reasons ingestion is paused for; it stays paused until
+// every reason has been lifted.
+type pauseState struct {
+	mu      sync.Mutex
+	reasons map[string]bool
+	since   time.Time
+}
+
+// pause reports whether this call paused ingestion.
+func (p *pauseState) pause(reason string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.reasons[reason] {
+		return false
+	}
+	if p.reasons == nil {
+		p.reasons = make(map[string]bool)
+	}
+	p.reasons[reason] = true
+	if len(p.reasons) > 1 {
+		return false
+	}
+
+	p.since = time.Now()
+	logger.Log.Warn("Consumer paused", "reason", reason)
+	return true
+}
+
+// resume reports whether this call resumed ingestion.
+func (p *pauseState) resume(reason string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.reasons[reason] {
+		return false
+	}
+	delete(p.reasons, reason)
+	if len(p.reasons) > 0 {
+		logger.Log.Info("Consumer still paused", "resumed_reason", reason, "remaining", len(p.reasons))
+		return false
+	}
+
+	logger.Log.Info("Consumer resumed", "reason", reason, "paused_for", time.Since(p.since))
+	return true
+}
+
+func (p *pauseState) paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.reasons) > 0
+}
+
+func (p *pauseState) state() dto.PauseState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.reasons) == 0 {
+		return dto.PauseState{}
+	}
+	reasons := make([]string, 0, len(p.reasons))
+	for reason := range p.reasons {
+		reasons = append(reasons, reason)
+	}
+	slices.Sort(reasons)
+	return dto.PauseState{Paused: true, Reasons: reasons, Since: p.since}
+}
+
+// wait blocks while ingestion is paused and reports false once ctx is done.
+// Consumers that pull messages call it before every fetch.
+func (p *pauseState) wait(ctx context.Context) bool {
+	for p.paused() {
+		select {
+		case <-time.After(pausePollInterval):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
@@ -0,0 +1,52 @@
+package deadletter
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+const (
+	HeaderOriginalTopic     = "dlq-original-topic"
+	HeaderOriginalPartition = "dlq-original-partition"
+	HeaderOriginalOffset    = "dlq-original-offset"
+	HeaderStage             = "dlq-stage"
+	HeaderError             = "dlq-error"
+	HeaderFailedAt          = "dlq-failed-at"
+)
+
+type Publisher struct {
+	topic    string
+	producer interfaces.MessageProducer
+}
+
+func NewPublisher(topic string, producer interfaces.MessageProducer) *Publisher {
+	return &Publisher{
+		topic:    topic,
+		producer: producer,
+	}
+}
+
+func (p *Publisher) PublishDeadLetter(value []byte, source dto.MessageMetadata, stage string, cause error) error {
+	headers := map[string]string{
+		HeaderOriginalTopic:     source.Topic,
+		HeaderOriginalPartition: strconv.FormatInt(int64(source.Partition), 10),
+		HeaderOriginalOffset:    strconv.FormatInt(source.Offset, 10),
+		HeaderStage:             stage,
+		HeaderFailedAt:          time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if cause != nil {
+		headers[HeaderError] = cause.Error()
+	}
+
+	if err := p.producer.Produce(p.topic, source.Key, value, headers); err != nil {
+		logger.Log.Error("Failed to publish dead letter", "topic", p.topic, "source_topic", source.Topic, "partition", source.Partition, "offset", source.Offset, "stage", stage, "error", err)
+		return err
+	}
+
+	logger.Log.Warn("Message sent to dead-letter topic", "topic", p.topic, "source_topic", source.Topic, "partition", source.Partition, "offset", source.Offset, "stage", stage, "cause", cause)
+	return nil
+}
@@ -0,0 +1,195 @@
+package deadletter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+)
+
+const (
+	HeaderReplayedFrom = "dlq-replayed-from"
+
+	readTimeout = 10 * time.Second
+)
+
+// Store reads the dead-letter topic directly from the brokers without joining
+// a consumer group, so inspecting it never moves any committed offsets. Every
+// read uses a consumer of its own: a sarama consumer only lets one reader at a
+// time consume a partition, and requests may read the same one concurrently.
+type Store struct {
+	topic       string
+	replayTopic string
+	client      sarama.Client
+	producer    interfaces.MessageProducer
+}
+
+func NewStore(cfg kafka.Config, topic string, producer interfaces.MessageProducer) (*Store, error) {
+	client, err := sarama.NewClient(cfg.Brokers, cfg.Sarama())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		topic:       topic,
+		replayTopic: cfg.Topics[0],
+		client:      client,
+		producer:    producer,
+	}, nil
+}
+
+func (s *Store) Close() error {
+	return s.client.Close()
+}
+
+// List returns up to limit dead letters starting at offset from in every
+// requested partition. A negative partition means all partitions.
+func (s *Store) List(partition int32, from int64, limit int) ([]dto.DeadLetter, error) {
+	partitions, err := s.partitions(partition)
+	if err != nil {
+		return nil, err
+	}
+
+	var letters []dto.DeadLetter
+	for _, p := range partitions {
+		if len(letters) >= limit {
+			break
+		}
+		batch, err := s.read(p, from, limit-len(letters))
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range batch {
+			letters = append(letters, toDeadLetter(msg, false))
+		}
+	}
+
+	return letters, nil
+}
+
+func (s *Store) Get(partition int32, offset int64) (*dto.DeadLetter, error) {
+	batch, err := s.read(partition, offset, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(batch) == 0 || batch[0].Offset != offset {
+		return nil, nil
+	}
+
+	letter := toDeadLetter(batch[0], true)
+	return &letter, nil
+}
+
+func (s *Store) Republish(letter *dto.DeadLetter, payload []byte) (string, error) {
+	topic := letter.OriginalTopic
+	if topic == "" {
+		topic = s.replayTopic
+	}
+
+	headers := map[string]string{
+		HeaderReplayedFrom: fmt.Sprintf("%s/%d/%d", s.topic, letter.Partition, letter.Offset),
+	}
+	if err := s.producer.Produce(topic, letter.Key, payload, headers); err != nil {
+		return "", err
+	}
+
+	return topic, nil
+}
+
+func (s *Store) partitions(partition int32) ([]int32, error) {
+	if partition >= 0 {
+		return []int32{partition}, nil
+	}
+	return s.client.Partitions(s.topic)
+}
+
+func (s *Store) read(partition int32, from int64, limit int) ([]*sarama.ConsumerMessage, error) {
+	oldest, err := s.client.GetOffset(s.topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return nil, err
+	}
+	newest, err := s.client.GetOffset(s.topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+	if from < oldest {
+		from = oldest
+	}
+	if from >= newest || limit <= 0 {
+		return nil, nil
+	}
+
+	// Closing a consumer created from the client leaves the client open.
+	consumer, err := sarama.NewConsumerFromClient(s.client)
+	if err != nil {
+		return nil, err
+	}
+	defer consumer.Close()
+
+	pc, err := consumer.ConsumePartition(s.topic, partition, from)
+	if err != nil {
+		return nil, err
+	}
+	defer pc.Close()
+
+	timeout := time.NewTimer(readTimeout)
+	defer timeout.Stop()
+
+	var batch []*sarama.ConsumerMessage
+	for len(batch) < limit {
+		select {
+		case msg := <-pc.Messages():
+			batch = append(batch, msg)
+			if msg.Offset >= newest-1 {
+				return batch, nil
+			}
+		case err := <-pc.Errors():
+			return nil, err
+		case <-timeout.C:
+			return batch, nil
+		}
+	}
+
+	return batch, nil
+}
+
+func toDeadLetter(msg *sarama.ConsumerMessage, withPayload bool) dto.DeadLetter {
+	letter := dto.DeadLetter{
+		Partition:   msg.Partition,
+		Offset:      msg.Offset,
+		Key:         string(msg.Key),
+		Timestamp:   msg.Timestamp,
+		PayloadSize: len(msg.Value),
+	}
+	if withPayload {
+		letter.Payload = string(msg.Value)
+	}
+
+	for _, h := range msg.Headers {
+		value := string(h.Value)
+		switch string(h.Key) {
+		case HeaderOriginalTopic:
+			letter.OriginalTopic = value
+		case HeaderOriginalPartition:
+			if p, err := strconv.ParseInt(value, 10, 32); err == nil {
+				letter.OriginalPartition = int32(p)
+			}
+		case HeaderOriginalOffset:
+			if o, err := strconv.ParseInt(value, 10, 64); err == nil {
+				letter.OriginalOffset = o
+			}
+		case HeaderStage:
+			letter.Stage = value
+		case HeaderError:
+			letter.Error = value
+		case HeaderFailedAt:
+			letter.FailedAt = value
+		}
+	}
+
+	return letter
+}
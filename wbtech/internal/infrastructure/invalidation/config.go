@@ -0,0 +1,32 @@
+package invalidation
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+type Config struct {
+	config.Invalidation
+	// Origin names this process in the events it publishes, so the listener
+	// can skip its own.
+	Origin string
+}
+
+// NewConfig takes the origin from the replica id, or the hostname when it is
+// not set. A random suffix keeps processes that share either apart, such as
+// the replay tool running next to the service.
+func NewConfig(c config.Invalidation) Config {
+	replica := c.ReplicaID
+	if replica == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Log.Warn("Failed to resolve hostname for replica id", "error", err)
+		}
+		replica = hostname
+	}
+	return Config{Invalidation: c, Origin: fmt.Sprintf("%s-%s", replica, rand.Text()[:8])}
+}
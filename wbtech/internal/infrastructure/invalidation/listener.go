@@ -0,0 +1,146 @@
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+const retryInterval = 5 * time.Second
+
+// Listener reads every partition of the invalidation topic without a consumer
+// group, so each replica sees every event. It starts from the newest offset:
+// events published before start-up are already reflected in the warm-up.
+//
+// Only caches local to this process are invalidated. The publishing replica
+// has already updated any shared cache, and deleting from it would throw
+// away the order it just stored.
+type Listener struct {
+	cfg      Config
+	kafka    kafka.Config
+	cache    interfaces.OrderCache
+	notFound interfaces.NegativeCache
+}
+
+// NewListener takes the process-local part of the order cache, see
+// cache.Local; it may be nil when there is none.
+func NewListener(cfg Config, kafkaCfg kafka.Config, cache interfaces.OrderCache, notFound interfaces.NegativeCache) *Listener {
+	return &Listener{
+		cfg:      cfg,
+		kafka:    kafkaCfg,
+		cache:    cache,
+		notFound: notFound,
+	}
+}
+
+func (l *Listener) Listen(ctx context.Context) {
+	if !l.cfg.Enabled {
+		return
+	}
+
+	for ctx.Err() == nil {
+		consumer, err := sarama.NewConsumer(l.kafka.Brokers, l.kafka.Sarama())
+		if err != nil {
+			logger.Log.Error("Couldn't create cache invalidation consumer", "error", err)
+			sleep(ctx, retryInterval)
+			continue
+		}
+
+		err = l.consumeAll(ctx, consumer)
+		consumer.Close()
+		if err != nil {
+			logger.Log.Error("Cache invalidation consumer stopped", "error", err)
+			sleep(ctx, retryInterval)
+			continue
+		}
+		return
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
+
+func (l *Listener) consumeAll(ctx context.Context, consumer sarama.Consumer) error {
+	partitions, err := consumer.Partitions(l.cfg.Topic)
+	if err != nil {
+		return err
+	}
+
+	var pcs []sarama.PartitionConsumer
+	for _, partition := range partitions {
+		pc, err := consumer.ConsumePartition(l.cfg.Topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			for _, pc := range pcs {
+				pc.AsyncClose()
+			}
+			return err
+		}
+		pcs = append(pcs, pc)
+	}
+
+	logger.Log.Info("Listening for cache invalidations", "topic", l.cfg.Topic, "partitions", len(partitions), "origin", l.cfg.Origin)
+
+	done := make(chan struct{})
+	for _, pc := range pcs {
+		go func() {
+			for err := range pc.Errors() {
+				logger.Log.Error("Cache invalidation partition error", "error", err)
+			}
+		}()
+		go func() {
+			for msg := range pc.Messages() {
+				l.apply(msg.Value)
+			}
+			done <- struct{}{}
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		for _, pc := range pcs {
+			pc.AsyncClose()
+		}
+	}()
+	for range pcs {
+		<-done
+	}
+
+	return nil
+}
+
+func (l *Listener) apply(payload []byte) {
+	var event dto.CacheInvalidation
+	if err := json.Unmarshal(payload, &event); err != nil {
+		logger.Log.Error("Failed to decode cache invalidation", "error", err)
+		return
+	}
+	if event.Origin == l.cfg.Origin {
+		return
+	}
+
+	switch event.Reason {
+	case dto.InvalidationFlushed:
+		if l.cache != nil {
+			l.cache.Flush()
+		}
+	default:
+		if event.OrderUID == "" {
+			return
+		}
+		if l.cache != nil {
+			l.cache.Delete(event.OrderUID)
+		}
+		l.notFound.Delete(event.OrderUID)
+	}
+
+	logger.Log.Info("Cache invalidation applied", "order_uid", event.OrderUID, "reason", event.Reason, "origin", event.Origin)
+}
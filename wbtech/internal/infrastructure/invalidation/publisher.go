@@ -0,0 +1,190 @@
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/logger"
+	"github.com/agl/wbtech/pkg/retry"
+)
+
+const (
+	queueSize = 4096
+	// batchSize bounds how many queued events are collapsed and sent in one
+	// round.
+	batchSize = 256
+)
+
+var sendRetry = retry.Policy{
+	MaxAttempts:    5,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// Publisher sends invalidations from a background goroutine, so a slow or
+// unavailable broker does not hold up the writes that trigger them. Events
+// queued while a round is being sent are collapsed per order and retried
+// before they count as failed. When the queue is full new events are
+// dropped; other replicas then serve the old order until their L1 TTL runs
+// out.
+type Publisher struct {
+	cfg      Config
+	producer interfaces.MessageProducer
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan dto.CacheInvalidation
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	published atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func NewPublisher(cfg Config, producer interfaces.MessageProducer) *Publisher {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Publisher{
+		cfg:      cfg,
+		producer: producer,
+		queue:    make(chan dto.CacheInvalidation, queueSize),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go p.run()
+	return p
+}
+
+func (p *Publisher) PublishInvalidation(orderUID, reason string) {
+	if !p.cfg.Enabled {
+		return
+	}
+
+	event := dto.CacheInvalidation{
+		OrderUID: orderUID,
+		Reason:   reason,
+		Origin:   p.cfg.Origin,
+		At:       time.Now(),
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		logger.Log.Warn("Cache invalidation publisher closed, dropping event", "order_uid", orderUID, "reason", reason)
+		return
+	}
+	select {
+	case p.queue <- event:
+	default:
+		p.dropped.Add(1)
+		logger.Log.Warn("Cache invalidation queue full, dropping event", "order_uid", orderUID, "reason", reason)
+	}
+}
+
+func (p *Publisher) Stats() dto.InvalidationStats {
+	return dto.InvalidationStats{
+		Published: p.published.Load(),
+		Failed:    p.failed.Load(),
+		Dropped:   p.dropped.Load(),
+		Queued:    len(p.queue),
+	}
+}
+
+// Close sends the queued events and stops the publisher. Retries are given up
+// once ctx is done, and whatever could not be sent by then counts as failed.
+func (p *Publisher) Close(ctx context.Context) {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.cancel()
+		<-p.done
+	}
+	p.cancel()
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	for event := range p.queue {
+		batch := []dto.CacheInvalidation{event}
+	collect:
+		for len(batch) < batchSize {
+			select {
+			case event, ok := <-p.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, event)
+			default:
+				break collect
+			}
+		}
+
+		for _, event := range collapse(batch) {
+			p.send(event)
+		}
+	}
+}
+
+// collapse keeps the last event for each order. A flush supersedes every event
+// queued before it.
+func collapse(batch []dto.CacheInvalidation) []dto.CacheInvalidation {
+	for i := len(batch) - 1; i >= 0; i-- {
+		if batch[i].Reason == dto.InvalidationFlushed {
+			batch = batch[i:]
+			break
+		}
+	}
+
+	last := make(map[string]int, len(batch))
+	for i, event := range batch {
+		last[event.OrderUID] = i
+	}
+	events := make([]dto.CacheInvalidation, 0, len(last))
+	for i, event := range batch {
+		if last[event.OrderUID] == i {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (p *Publisher) send(event dto.CacheInvalidation) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		p.failed.Add(1)
+		logger.Log.Error("Failed to encode cache invalidation", "order_uid", event.OrderUID, "error", err)
+		return
+	}
+
+	err = retry.Do(p.ctx, sendRetry, func(int) error {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+		return p.producer.Produce(p.cfg.Topic, event.OrderUID, payload, nil)
+	})
+	if err != nil {
+		p.failed.Add(1)
+		logger.Log.Error("Failed to publish cache invalidation", "order_uid", event.OrderUID, "reason", event.Reason, "error", err)
+		return
+	}
+
+	p.published.Add(1)
+	logger.Log.Debug("Cache invalidation published", "order_uid", event.OrderUID, "reason", event.Reason)
+}
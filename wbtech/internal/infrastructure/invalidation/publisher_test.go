@@ -0,0 +1,134 @@
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+type fakeProducer struct {
+	mu       sync.Mutex
+	failures int
+	sent     []dto.CacheInvalidation
+}
+
+func (p *fakeProducer) Produce(topic string, key string, payload []byte, headers map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	var event dto.CacheInvalidation
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	p.sent = append(p.sent, event)
+	return nil
+}
+
+func testPublisher(producer *fakeProducer) *Publisher {
+	return NewPublisher(Config{Invalidation: config.Invalidation{Enabled: true, Topic: "invalidations"}, Origin: "test"}, producer)
+}
+
+func TestCollapse(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch []string
+		want  []string
+	}{
+		{
+			name:  "distinct orders are kept in order",
+			batch: []string{"a", "b", "c"},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "last event per order wins",
+			batch: []string{"a", "b", "a"},
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "flush supersedes earlier events",
+			batch: []string{"a", "", "b"},
+			want:  []string{"", "b"},
+		},
+		{
+			name:  "last flush wins",
+			batch: []string{"", "a", "", "b"},
+			want:  []string{"", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batch []dto.CacheInvalidation
+			for _, uid := range tt.batch {
+				event := dto.CacheInvalidation{OrderUID: uid, Reason: dto.InvalidationStored}
+				if uid == "" {
+					event.Reason = dto.InvalidationFlushed
+				}
+				batch = append(batch, event)
+			}
+
+			var got []string
+			for _, event := range collapse(batch) {
+				got = append(got, event.OrderUID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("collapse = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublisherRetries(t *testing.T) {
+	producer := &fakeProducer{failures: 2}
+	p := testPublisher(producer)
+	p.PublishInvalidation("a", dto.InvalidationStored)
+	p.Close(context.Background())
+
+	if len(producer.sent) != 1 || producer.sent[0].OrderUID != "a" || producer.sent[0].Origin != "test" {
+		t.Errorf("sent = %+v, want one event for a from test", producer.sent)
+	}
+	if stats := p.Stats(); stats != (dto.InvalidationStats{Published: 1}) {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestPublisherCloseGivesUp(t *testing.T) {
+	producer := &fakeProducer{failures: 1000}
+	p := testPublisher(producer)
+	p.PublishInvalidation("a", dto.InvalidationStored)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p.Close(ctx)
+
+	if stats := p.Stats(); stats.Failed != 1 {
+		t.Errorf("failed = %d, want 1", stats.Failed)
+	}
+
+	p.PublishInvalidation("b", dto.InvalidationStored)
+	if stats := p.Stats(); stats.Dropped != 1 {
+		t.Errorf("dropped after close = %d, want 1", stats.Dropped)
+	}
+}
+
+func TestPublisherDisabled(t *testing.T) {
+	producer := &fakeProducer{}
+	p := NewPublisher(Config{}, producer)
+	p.PublishInvalidation("a", dto.InvalidationStored)
+	p.Close(context.Background())
+
+	if len(producer.sent) != 0 {
+		t.Errorf("sent %d events while disabled", len(producer.sent))
+	}
+}
@@ -0,0 +1,138 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/kafkasecurity"
+)
+
+const (
+	OffsetOldest = "oldest"
+	OffsetNewest = "newest"
+
+	RebalanceRange      = "range"
+	RebalanceRoundRobin = "roundrobin"
+	RebalanceSticky     = "sticky"
+)
+
+// Config is the validated Kafka section of the service configuration.
+type Config struct {
+	config.Kafka
+
+	// security is loaded once, so every client, including reconnects, uses
+	// the same certificates and a bad file fails start-up.
+	security *kafkasecurity.Security
+}
+
+// NewConfig normalizes the case of the enumerated settings, validates c and
+// loads the TLS certificates.
+func NewConfig(c config.Kafka) (Config, error) {
+	c.InitialOffset = strings.ToLower(c.InitialOffset)
+	c.RebalanceStrategy = strings.ToLower(c.RebalanceStrategy)
+
+	cfg := Config{Kafka: c}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	security, err := kafkasecurity.New(c.TLS, c.SASL)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid kafka config: %w", err)
+	}
+	cfg.security = security
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if len(c.Brokers) == 0 {
+		errs = append(errs, errors.New("at least one broker is required"))
+	}
+	for _, broker := range c.Brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			errs = append(errs, fmt.Errorf("broker %q: %w", broker, err))
+		}
+	}
+	if len(c.Topics) == 0 {
+		errs = append(errs, errors.New("at least one topic is required"))
+	}
+	if c.GroupID == "" {
+		errs = append(errs, errors.New("group id is required"))
+	}
+	if _, err := sarama.ParseKafkaVersion(c.Version); err != nil {
+		errs = append(errs, fmt.Errorf("version: %w", err))
+	}
+	if c.InitialOffset != OffsetOldest && c.InitialOffset != OffsetNewest {
+		errs = append(errs, fmt.Errorf("initial offset %q: must be %s or %s", c.InitialOffset, OffsetOldest, OffsetNewest))
+	}
+	if c.SessionTimeout <= 0 {
+		errs = append(errs, errors.New("session timeout must be positive"))
+	}
+	if c.HeartbeatInterval <= 0 || c.HeartbeatInterval >= c.SessionTimeout {
+		errs = append(errs, errors.New("heartbeat interval must be positive and shorter than the session timeout"))
+	}
+	if c.FetchMinBytes < 1 {
+		errs = append(errs, errors.New("fetch min bytes must be at least 1"))
+	}
+	if c.FetchDefaultBytes < c.FetchMinBytes {
+		errs = append(errs, errors.New("fetch default bytes must not be below fetch min bytes"))
+	}
+	if c.FetchMaxBytes != 0 && c.FetchMaxBytes < c.FetchDefaultBytes {
+		errs = append(errs, errors.New("fetch max bytes must be 0 (unlimited) or at least fetch default bytes"))
+	}
+	if _, err := c.rebalanceStrategy(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid kafka config: %w", err)
+	}
+	return nil
+}
+
+func (c Config) rebalanceStrategy() (sarama.BalanceStrategy, error) {
+	switch c.RebalanceStrategy {
+	case RebalanceRange:
+		return sarama.NewBalanceStrategyRange(), nil
+	case RebalanceRoundRobin:
+		return sarama.NewBalanceStrategyRoundRobin(), nil
+	case RebalanceSticky:
+		return sarama.NewBalanceStrategySticky(), nil
+	default:
+		return nil, fmt.Errorf("rebalance strategy %q: must be %s, %s or %s", c.RebalanceStrategy, RebalanceRange, RebalanceRoundRobin, RebalanceSticky)
+	}
+}
+
+// Sarama returns the client settings shared by consumers and producers.
+func (c Config) Sarama() *sarama.Config {
+	config := sarama.NewConfig()
+	config.Version, _ = sarama.ParseKafkaVersion(c.Version)
+	config.Consumer.Return.Errors = true
+	config.Consumer.Fetch.Min = c.FetchMinBytes
+	config.Consumer.Fetch.Default = c.FetchDefaultBytes
+	config.Consumer.Fetch.Max = c.FetchMaxBytes
+	c.security.Apply(config)
+	return config
+}
+
+// ConsumerGroup returns the settings for the order consumer group.
+func (c Config) ConsumerGroup() *sarama.Config {
+	config := c.Sarama()
+	config.Consumer.Group.Session.Timeout = c.SessionTimeout
+	config.Consumer.Group.Heartbeat.Interval = c.HeartbeatInterval
+	if strategy, err := c.rebalanceStrategy(); err == nil {
+		config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{strategy}
+	}
+	if c.InitialOffset == OffsetOldest {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	} else {
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+	return config
+}
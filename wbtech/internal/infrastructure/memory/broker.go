@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+type Record struct {
+	Offset    int64
+	Key       string
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+type topicLog struct {
+	records   []Record
+	committed int64
+	// notify is closed and replaced whenever a record is appended.
+	notify chan struct{}
+}
+
+// Broker is an in-process stand-in for Kafka for local runs and tests. Every
+// topic is a single partition log kept in memory, with one committed offset
+// shared by all consumers.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]*topicLog
+}
+
+func NewBroker() *Broker {
+	return &Broker{topics: make(map[string]*topicLog)}
+}
+
+func (b *Broker) topic(name string) *topicLog {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topicLog{notify: make(chan struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (b *Broker) Produce(topic string, key string, payload []byte, headers map[string]string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	t.records = append(t.records, Record{
+		Offset:    int64(len(t.records)),
+		Key:       key,
+		Value:     append([]byte(nil), payload...),
+		Headers:   maps.Clone(headers),
+		Timestamp: time.Now(),
+	})
+	close(t.notify)
+	t.notify = make(chan struct{})
+	return nil
+}
+
+// Fetch returns the record at offset, waiting for it to be produced until ctx
+// is done.
+func (b *Broker) Fetch(ctx context.Context, topic string, offset int64) (Record, error) {
+	for {
+		b.mu.Lock()
+		t := b.topic(topic)
+		if offset < int64(len(t.records)) {
+			rec := t.records[offset]
+			b.mu.Unlock()
+			return rec, nil
+		}
+		notify := t.notify
+		b.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return Record{}, ctx.Err()
+		}
+	}
+}
+
+// Commit stores next as the offset to resume topic from. Like Kafka offset
+// marking, it never moves the committed offset backwards.
+func (b *Broker) Commit(topic string, next int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	t.committed = max(t.committed, next)
+}
+
+func (b *Broker) Committed(topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.topic(topic).committed
+}
+
+// Seed publishes every element of the JSON array in path to topic.
+func (b *Broker) Seed(topic, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var messages []json.RawMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, msg := range messages {
+		if err := b.Produce(topic, "", msg, nil); err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Info("In-memory broker seeded", "topic", topic, "file", path, "messages", len(messages))
+	return nil
+}
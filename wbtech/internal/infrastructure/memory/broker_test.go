@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBrokerProduceFetch(t *testing.T) {
+	b := NewBroker()
+	payload := []byte(`{"order_uid":"a"}`)
+	headers := map[string]string{"h": "1"}
+	b.Produce("orders", "a", payload, headers)
+	b.Produce("orders", "b", []byte(`{}`), nil)
+	b.Produce("other", "c", []byte(`{}`), nil)
+
+	// The broker keeps its own copies.
+	payload[0] = 'x'
+	headers["h"] = "2"
+
+	rec, err := b.Fetch(context.Background(), "orders", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Offset != 0 || rec.Key != "a" || string(rec.Value) != `{"order_uid":"a"}` || rec.Headers["h"] != "1" {
+		t.Errorf("record 0 = %+v", rec)
+	}
+	if rec, _ := b.Fetch(context.Background(), "orders", 1); rec.Offset != 1 || rec.Key != "b" {
+		t.Errorf("record 1 = %+v", rec)
+	}
+	if rec, _ := b.Fetch(context.Background(), "other", 0); rec.Key != "c" {
+		t.Errorf("topics share a log: %+v", rec)
+	}
+}
+
+func TestBrokerFetchWaits(t *testing.T) {
+	b := NewBroker()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Produce("orders", "a", nil, nil)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if rec, err := b.Fetch(ctx, "orders", 0); err != nil || rec.Key != "a" {
+		t.Errorf("fetch = %+v, %v; want the record produced later", rec, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := b.Fetch(ctx, "orders", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetch past the end = %v, want the context error", err)
+	}
+}
+
+func TestBrokerCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits []int64
+		want    int64
+	}{
+		{name: "nothing committed", want: 0},
+		{name: "moves forward", commits: []int64{1, 3}, want: 3},
+		{name: "never moves backwards", commits: []int64{5, 2}, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker()
+			for _, next := range tt.commits {
+				b.Commit("orders", next)
+			}
+			if got := b.Committed("orders"); got != tt.want {
+				t.Errorf("committed = %d, want %d", got, tt.want)
+			}
+			if got := b.Committed("other"); got != 0 {
+				t.Errorf("other topic committed = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestBrokerSeed(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "array of orders", path: write("orders.json", `[{"order_uid":"a"}, {"order_uid":"b"}]`), want: []string{`{"order_uid":"a"}`, `{"order_uid":"b"}`}},
+		{name: "empty array", path: write("empty.json", `[]`)},
+		{name: "not an array", path: write("object.json", `{"order_uid":"a"}`), wantErr: true},
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker()
+			err := b.Seed("orders", tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+
+			b.mu.Lock()
+			records := b.topic("orders").records
+			b.mu.Unlock()
+			if len(records) != len(tt.want) {
+				t.Fatalf("seeded %d records, want %d", len(records), len(tt.want))
+			}
+			for i, rec := range records {
+				if string(rec.Value) != tt.want[i] || rec.Offset != int64(i) {
+					t.Errorf("record %d = %d %s, want %s", i, rec.Offset, rec.Value, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -2,23 +2,22 @@ package migrations
 
 import (
 	"database/sql"
-	"os"
 
+	"github.com/agl/wbtech/pkg/config"
 	"github.com/agl/wbtech/pkg/logger"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
-func RunMigrationsPG(db *sql.DB) {
+func RunMigrationsPG(db *sql.DB, cfg config.Migrations) {
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
 		logger.Log.Error("failed to create pg driver", "err", err)
 		return
 	}
 
-	migrationsPath := os.Getenv("MIGRATIONS_PATH")
-	m, err := migrate.NewWithDatabaseInstance(migrationsPath, "postgres", driver)
+	m, err := migrate.NewWithDatabaseInstance(cfg.Path, "postgres", driver)
 	if err != nil {
 		logger.Log.Error("failed to create migrate instance", "err", err)
 		return
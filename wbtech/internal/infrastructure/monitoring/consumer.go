@@ -0,0 +1,230 @@
+package monitoring
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+// ConsumerMonitor compares the committed offsets of the consumer group with the
+// partition high-water marks and aggregates the throughput and latency reported
+// by the message handler over each polling interval. Without a Kafka config
+// only throughput and latency are reported.
+type ConsumerMonitor struct {
+	cfg   config.Monitoring
+	kafka *kafka.Config
+
+	mu        sync.Mutex
+	processed uint64
+	failed    uint64
+	// Counters for the current interval; reset on every poll.
+	count      uint64
+	latencySum time.Duration
+	latencyMax time.Duration
+	lastPoll   time.Time
+	lastCount  uint64
+	stats      dto.ConsumerStats
+}
+
+func NewConsumerMonitor(cfg config.Monitoring, kafkaCfg *kafka.Config) *ConsumerMonitor {
+	m := &ConsumerMonitor{
+		cfg:      cfg,
+		kafka:    kafkaCfg,
+		lastPoll: time.Now(),
+		stats: dto.ConsumerStats{
+			LagThreshold: cfg.LagThreshold,
+			Healthy:      true,
+		},
+	}
+	if kafkaCfg != nil {
+		m.stats.Group = kafkaCfg.GroupID
+	}
+	return m
+}
+
+func (m *ConsumerMonitor) RecordProcessed(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.processed++
+	m.record(latency)
+}
+
+func (m *ConsumerMonitor) RecordFailed(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failed++
+	m.record(latency)
+}
+
+func (m *ConsumerMonitor) record(latency time.Duration) {
+	m.count++
+	m.latencySum += latency
+	m.latencyMax = max(m.latencyMax, latency)
+}
+
+func (m *ConsumerMonitor) Stats() dto.ConsumerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats
+	stats.Partitions = append([]dto.PartitionLag(nil), m.stats.Partitions...)
+	stats.Processed = m.processed
+	stats.Failed = m.failed
+	return stats
+}
+
+func (m *ConsumerMonitor) Ready() error {
+	stats := m.Stats()
+	if !stats.Healthy {
+		return fmt.Errorf("consumer lag %d exceeds threshold %d", stats.TotalLag, m.cfg.LagThreshold)
+	}
+	return nil
+}
+
+// Run polls the lag until ctx is cancelled. The brokers are contacted lazily,
+// so a broker outage only shows up as a lag error in the stats.
+func (m *ConsumerMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.LagInterval)
+	defer ticker.Stop()
+
+	// Closing the admin also closes the client it was created from.
+	var client sarama.Client
+	var admin sarama.ClusterAdmin
+	defer func() {
+		if admin != nil {
+			admin.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if m.kafka == nil {
+			m.update(nil, nil)
+			continue
+		}
+		if admin == nil {
+			var err error
+			if client, err = sarama.NewClient(m.kafka.Brokers, m.kafka.Sarama()); err != nil {
+				m.update(nil, err)
+				continue
+			}
+			if admin, err = sarama.NewClusterAdminFromClient(client); err != nil {
+				client.Close()
+				m.update(nil, err)
+				continue
+			}
+		}
+
+		partitions, err := m.lag(client, admin)
+		if err != nil {
+			admin.Close()
+			admin = nil
+		}
+		m.update(partitions, err)
+	}
+}
+
+func (m *ConsumerMonitor) lag(client sarama.Client, admin sarama.ClusterAdmin) ([]dto.PartitionLag, error) {
+	topics, err := admin.DescribeTopics(m.kafka.Topics)
+	if err != nil {
+		return nil, err
+	}
+
+	request := make(map[string][]int32)
+	for _, t := range topics {
+		if t.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("topic %s: %w", t.Name, t.Err)
+		}
+		for _, p := range t.Partitions {
+			request[t.Name] = append(request[t.Name], p.ID)
+		}
+	}
+
+	committed, err := admin.ListConsumerGroupOffsets(m.kafka.GroupID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []dto.PartitionLag
+	for topic, ids := range request {
+		for _, id := range ids {
+			hwm, err := client.GetOffset(topic, id, sarama.OffsetNewest)
+			if err != nil {
+				return nil, err
+			}
+
+			p := dto.PartitionLag{Topic: topic, Partition: id, HighWaterMark: hwm, Committed: -1}
+			if block := committed.GetBlock(topic, id); block != nil && block.Offset >= 0 {
+				p.Committed = block.Offset
+				p.Lag = max(hwm-block.Offset, 0)
+			} else {
+				// Nothing committed yet: everything retained is outstanding.
+				oldest, err := client.GetOffset(topic, id, sarama.OffsetOldest)
+				if err != nil {
+					return nil, err
+				}
+				p.Lag = hwm - oldest
+			}
+			partitions = append(partitions, p)
+		}
+	}
+
+	slices.SortFunc(partitions, func(a, b dto.PartitionLag) int {
+		return cmp.Or(strings.Compare(a.Topic, b.Topic), cmp.Compare(a.Partition, b.Partition))
+	})
+	return partitions, nil
+}
+
+func (m *ConsumerMonitor) update(partitions []dto.PartitionLag, lagErr error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	total := m.processed + m.failed
+	if elapsed := now.Sub(m.lastPoll).Seconds(); elapsed > 0 {
+		m.stats.MessagesPerSecond = float64(total-m.lastCount) / elapsed
+	}
+	m.stats.AvgLatencyMs = 0
+	if m.count > 0 {
+		m.stats.AvgLatencyMs = float64(m.latencySum.Microseconds()) / float64(m.count) / 1000
+	}
+	m.stats.MaxLatencyMs = float64(m.latencyMax.Microseconds()) / 1000
+	m.lastPoll, m.lastCount = now, total
+	m.count, m.latencySum, m.latencyMax = 0, 0, 0
+
+	m.stats.UpdatedAt = now
+	m.stats.LagError = ""
+	if lagErr != nil {
+		logger.Log.Error("Failed to read consumer lag", "error", lagErr)
+		m.stats.LagError = lagErr.Error()
+	} else {
+		m.stats.Partitions = partitions
+		m.stats.TotalLag = 0
+		for _, p := range partitions {
+			m.stats.TotalLag += p.Lag
+		}
+	}
+
+	healthy := m.cfg.LagThreshold == 0 || m.stats.TotalLag <= m.cfg.LagThreshold
+	if healthy != m.stats.Healthy {
+		logger.Log.Warn("Consumer readiness changed", "healthy", healthy, "total_lag", m.stats.TotalLag, "threshold", m.cfg.LagThreshold)
+	}
+	m.stats.Healthy = healthy
+}
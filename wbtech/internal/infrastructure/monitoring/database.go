@@ -0,0 +1,54 @@
+package monitoring
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+// DatabaseWatcher pauses ingestion while the database does not answer pings,
+// so messages stay in Kafka instead of failing into the dead-letter topic,
+// and resumes it once the database is back.
+type DatabaseWatcher struct {
+	db        *sql.DB
+	ingestion interfaces.IngestionControl
+	interval  time.Duration
+}
+
+func NewDatabaseWatcher(db *sql.DB, ingestion interfaces.IngestionControl, interval time.Duration) *DatabaseWatcher {
+	return &DatabaseWatcher{
+		db:        db,
+		ingestion: ingestion,
+		interval:  interval,
+	}
+}
+
+func (w *DatabaseWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, w.interval)
+		err := w.db.PingContext(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			logger.Log.Error("Database unreachable", "error", err)
+			w.ingestion.Pause(interfaces.PauseReasonDatabase)
+		} else {
+			w.ingestion.Resume(interfaces.PauseReasonDatabase)
+		}
+	}
+}
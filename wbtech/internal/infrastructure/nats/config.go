@@ -0,0 +1,122 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/logger"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// HeaderKey carries the message key, which NATS has no field for.
+const HeaderKey = "Msg-Key"
+
+// Config is the validated NATS section of the service configuration.
+type Config struct {
+	config.NATS
+}
+
+func NewConfig(c config.NATS) (Config, error) {
+	cfg := Config{NATS: c}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.URL == "" {
+		errs = append(errs, errors.New("url is required"))
+	}
+	if c.Stream == "" {
+		errs = append(errs, errors.New("stream is required"))
+	}
+	if len(c.Subjects) == 0 {
+		errs = append(errs, errors.New("at least one subject is required"))
+	}
+	if c.Durable == "" {
+		errs = append(errs, errors.New("durable consumer name is required"))
+	}
+	if c.AckWait <= 0 {
+		errs = append(errs, errors.New("ack wait must be positive"))
+	}
+	if c.MaxAckPending < 1 {
+		errs = append(errs, errors.New("max ack pending must be at least 1"))
+	}
+	if c.FetchBatch < 1 || c.FetchBatch > c.MaxAckPending {
+		errs = append(errs, errors.New("fetch batch must be between 1 and max ack pending"))
+	}
+	if c.FetchMaxWait <= 0 || c.FetchMaxWait >= c.AckWait {
+		errs = append(errs, errors.New("fetch max wait must be positive and shorter than the ack wait"))
+	}
+	if c.NakDelay < 0 {
+		errs = append(errs, errors.New("nak delay must not be negative"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid nats config: %w", err)
+	}
+	return nil
+}
+
+func (c Config) Connect() (*natsgo.Conn, error) {
+	opts := []natsgo.Option{
+		natsgo.Name(c.Name),
+		natsgo.MaxReconnects(-1),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			logger.Log.Warn("Disconnected from NATS", "error", err)
+		}),
+		natsgo.ReconnectHandler(func(conn *natsgo.Conn) {
+			logger.Log.Info("Reconnected to NATS", "url", conn.ConnectedUrl())
+		}),
+	}
+	if c.CredsFile != "" {
+		opts = append(opts, natsgo.UserCredentials(c.CredsFile))
+	}
+
+	conn, err := natsgo.Connect(c.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("Connected to NATS", "url", conn.ConnectedUrl())
+	return conn, nil
+}
+
+// EnsureStream creates the stream with the consumed subjects and extra, e.g.
+// the dead-letter subject, unless it already exists. An existing stream is
+// left as it is.
+func (c Config) EnsureStream(ctx context.Context, conn *natsgo.Conn, extra ...string) error {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return err
+	}
+
+	_, err = js.Stream(ctx, c.Stream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return err
+	}
+
+	subjects := slices.Concat(c.Subjects, extra)
+	slices.Sort(subjects)
+	subjects = slices.Compact(subjects)
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     c.Stream,
+		Subjects: subjects,
+	}); err != nil {
+		return fmt.Errorf("create stream %s: %w", c.Stream, err)
+	}
+
+	logger.Log.Info("NATS stream created", "stream", c.Stream, "subjects", subjects)
+	return nil
+}
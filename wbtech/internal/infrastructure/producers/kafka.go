@@ -0,0 +1,54 @@
+package producers
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+type KafkaProducer struct {
+	kafka sarama.SyncProducer
+}
+
+func NewKafkaProducer(cfg kafka.Config) (*KafkaProducer, error) {
+	config := cfg.Sarama()
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, config)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("Kafka producer created successfully")
+
+	return &KafkaProducer{
+		kafka: producer,
+	}, nil
+}
+
+func (kp *KafkaProducer) Produce(topic string, key string, payload []byte, headers map[string]string) error {
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(payload),
+	}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	for k, v := range headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	partition, offset, err := kp.kafka.SendMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Debug("Message produced", "topic", topic, "partition", partition, "offset", offset)
+	return nil
+}
+
+func (kp *KafkaProducer) Close() error {
+	return kp.kafka.Close()
+}
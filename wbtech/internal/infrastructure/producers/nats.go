@@ -0,0 +1,52 @@
+package producers
+
+import (
+	"context"
+	"time"
+
+	"github.com/agl/wbtech/internal/infrastructure/nats"
+	"github.com/agl/wbtech/pkg/logger"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const natsPublishTimeout = 5 * time.Second
+
+// NATSProducer publishes to JetStream, so a message is only accepted once a
+// stream has stored it. Topics are used as subjects.
+type NATSProducer struct {
+	js jetstream.JetStream
+}
+
+func NewNATSProducer(conn *natsgo.Conn) (*NATSProducer, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NATSProducer{
+		js: js,
+	}, nil
+}
+
+func (np *NATSProducer) Produce(topic string, key string, payload []byte, headers map[string]string) error {
+	msg := natsgo.NewMsg(topic)
+	msg.Data = payload
+	if key != "" {
+		msg.Header.Set(nats.HeaderKey, key)
+	}
+	for k, v := range headers {
+		msg.Header.Set(k, v)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsPublishTimeout)
+	defer cancel()
+
+	ack, err := np.js.PublishMsg(ctx, msg)
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Debug("Message produced", "topic", topic, "stream", ack.Stream, "sequence", ack.Sequence)
+	return nil
+}
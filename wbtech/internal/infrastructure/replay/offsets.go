@@ -0,0 +1,195 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+)
+
+type targetKind int
+
+const (
+	targetEarliest targetKind = iota
+	targetLatest
+	targetOffset
+	targetTime
+)
+
+// Target is where a consumer group is moved to: the earliest or latest
+// retained offset, an absolute offset, or the first message at or after a
+// timestamp.
+type Target struct {
+	kind   targetKind
+	offset int64
+	time   time.Time
+}
+
+func ParseTarget(s string) (Target, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "earliest", "oldest":
+		return Target{kind: targetEarliest}, nil
+	case "latest", "newest":
+		return Target{kind: targetLatest}, nil
+	}
+	if offset, err := strconv.ParseInt(s, 10, 64); err == nil && offset >= 0 {
+		return Target{kind: targetOffset, offset: offset}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return Target{kind: targetTime, time: t}, nil
+	}
+	return Target{}, fmt.Errorf("invalid target %q: want earliest, latest, an offset or an RFC 3339 timestamp", s)
+}
+
+type PartitionOffset struct {
+	Partition int32
+	Current   int64
+	Target    int64
+}
+
+// Resetter moves the committed offsets of a consumer group. The group must have
+// no active members, otherwise they would overwrite the reset on their next
+// commit.
+type Resetter struct {
+	group  string
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+func NewResetter(cfg kafka.Config) (*Resetter, error) {
+	client, err := sarama.NewClient(cfg.Brokers, cfg.Sarama())
+	if err != nil {
+		return nil, err
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &Resetter{
+		group:  cfg.GroupID,
+		client: client,
+		admin:  admin,
+	}, nil
+}
+
+// Close also closes the underlying client.
+func (r *Resetter) Close() error {
+	return r.admin.Close()
+}
+
+// Plan resolves target for every requested partition of topic, a negative
+// partition meaning all of them, without changing anything.
+func (r *Resetter) Plan(topic string, partition int32, target Target) ([]PartitionOffset, error) {
+	partitions := []int32{partition}
+	if partition < 0 {
+		var err error
+		if partitions, err = r.client.Partitions(topic); err != nil {
+			return nil, err
+		}
+	}
+
+	committed, err := r.admin.ListConsumerGroupOffsets(r.group, map[string][]int32{topic: partitions})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]PartitionOffset, 0, len(partitions))
+	for _, p := range partitions {
+		current := int64(-1)
+		if block := committed.GetBlock(topic, p); block != nil {
+			if block.Err != sarama.ErrNoError {
+				return nil, fmt.Errorf("partition %d: %w", p, block.Err)
+			}
+			current = block.Offset
+		}
+
+		offset, err := r.resolve(topic, p, target)
+		if err != nil {
+			return nil, fmt.Errorf("partition %d: %w", p, err)
+		}
+		plan = append(plan, PartitionOffset{Partition: p, Current: current, Target: offset})
+	}
+
+	return plan, nil
+}
+
+func (r *Resetter) resolve(topic string, partition int32, target Target) (int64, error) {
+	oldest, err := r.client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, err
+	}
+	newest, err := r.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, err
+	}
+
+	switch target.kind {
+	case targetEarliest:
+		return oldest, nil
+	case targetLatest:
+		return newest, nil
+	case targetOffset:
+		return min(max(target.offset, oldest), newest), nil
+	default:
+		offset, err := r.client.GetOffset(topic, partition, target.time.UnixMilli())
+		if err != nil {
+			return 0, err
+		}
+		// No message at or after the timestamp: nothing to replay.
+		if offset < 0 {
+			return newest, nil
+		}
+		return offset, nil
+	}
+}
+
+// Apply commits the planned offsets for the group.
+func (r *Resetter) Apply(topic string, plan []PartitionOffset) error {
+	groups, err := r.admin.DescribeConsumerGroups([]string{r.group})
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if len(g.Members) > 0 {
+			return fmt.Errorf("consumer group %s has %d active members (state %s); stop the consumers before resetting offsets", r.group, len(g.Members), g.State)
+		}
+	}
+
+	om, err := sarama.NewOffsetManagerFromClient(r.group, r.client)
+	if err != nil {
+		return err
+	}
+	defer om.Close()
+
+	var poms []sarama.PartitionOffsetManager
+	for _, po := range plan {
+		pom, err := om.ManagePartition(topic, po.Partition)
+		if err != nil {
+			return err
+		}
+		poms = append(poms, pom)
+
+		// MarkOffset only moves forward and ResetOffset only backwards.
+		if next, _ := pom.NextOffset(); po.Target > next {
+			pom.MarkOffset(po.Target, "")
+		} else {
+			pom.ResetOffset(po.Target, "")
+		}
+	}
+	om.Commit()
+
+	var errs []error
+	for _, pom := range poms {
+		pom.AsyncClose()
+		for err := range pom.Errors() {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package replay
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/infrastructure/kafka"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+type Stats struct {
+	Messages int
+	Failed   int
+}
+
+// Replayer reads a time window of a topic without joining a consumer group, so
+// a one-off replay never touches the committed offsets of the service.
+type Replayer struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+}
+
+func NewReplayer(cfg kafka.Config) (*Replayer, error) {
+	client, err := sarama.NewClient(cfg.Brokers, cfg.Sarama())
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &Replayer{
+		client:   client,
+		consumer: consumer,
+	}, nil
+}
+
+func (r *Replayer) Close() error {
+	r.consumer.Close()
+	return r.client.Close()
+}
+
+// Run passes every message of topic with a timestamp in [from, to) to store,
+// partition by partition and in offset order. A zero to means up to the
+// messages present when the partition is reached. Failed messages are counted
+// and skipped.
+func (r *Replayer) Run(ctx context.Context, topic string, partition int32, from, to time.Time, store func(value []byte, md dto.MessageMetadata) error) (Stats, error) {
+	partitions := []int32{partition}
+	if partition < 0 {
+		var err error
+		if partitions, err = r.client.Partitions(topic); err != nil {
+			return Stats{}, err
+		}
+	}
+
+	var stats Stats
+	for _, p := range partitions {
+		if err := r.runPartition(ctx, topic, p, from, to, store, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func (r *Replayer) runPartition(ctx context.Context, topic string, partition int32, from, to time.Time, store func([]byte, dto.MessageMetadata) error, stats *Stats) error {
+	start, err := r.offsetAt(topic, partition, from)
+	if err != nil {
+		return err
+	}
+	end, err := r.offsetAt(topic, partition, to)
+	if err != nil {
+		return err
+	}
+	if start >= end {
+		logger.Log.Info("Nothing to replay", "topic", topic, "partition", partition)
+		return nil
+	}
+
+	logger.Log.Info("Replaying partition", "topic", topic, "partition", partition, "from_offset", start, "to_offset", end)
+
+	pc, err := r.consumer.ConsumePartition(topic, partition, start)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+
+	for {
+		select {
+		case msg := <-pc.Messages():
+			stats.Messages++
+			md := dto.MessageMetadata{
+				Topic:     msg.Topic,
+				Partition: msg.Partition,
+				Offset:    msg.Offset,
+				Key:       string(msg.Key),
+				Timestamp: msg.Timestamp,
+			}
+			if err := store(msg.Value, md); err != nil {
+				stats.Failed++
+				logger.Log.Error("Failed to replay message", "partition", msg.Partition, "offset", msg.Offset, "error", err)
+			}
+			if msg.Offset >= end-1 {
+				return nil
+			}
+		case err := <-pc.Errors():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// offsetAt returns the first offset at or after t, or the newest offset when t
+// is zero or later than every message.
+func (r *Replayer) offsetAt(topic string, partition int32, t time.Time) (int64, error) {
+	if !t.IsZero() {
+		offset, err := r.client.GetOffset(topic, partition, t.UnixMilli())
+		if err != nil || offset >= 0 {
+			return offset, err
+		}
+	}
+	return r.client.GetOffset(topic, partition, sarama.OffsetNewest)
+}
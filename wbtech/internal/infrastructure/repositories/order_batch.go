@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/logger"
+	"github.com/agl/wbtech/pkg/retry"
+)
+
+// Postgres accepts at most 65535 bind parameters per statement.
+const maxStatementParams = 65535
+
+var errDuplicateInBatch = errors.New("order appears more than once in batch")
+
+// StoreOrders writes a batch of orders in one transaction using multi-row
+// inserts. Orders that already exist go through the same conflict resolution
+// as StoreOrder. Either the whole batch is committed or none of it is.
+func (r *OrderRepository) StoreOrders(ctx context.Context, orders []*entities.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.WriteTimeout)
+	defer cancel()
+
+	outcomes, err := r.upsertOrders(ctx, orders)
+	if err != nil {
+		logger.Log.Error("Failed to store order batch", "count", len(orders), "error", err)
+		return classifyError(err)
+	}
+
+	for i, order := range orders {
+		r.applyOutcome(order, outcomes[i])
+	}
+	logger.Log.Info("Order batch stored", "count", len(orders))
+
+	return nil
+}
+
+func (r *OrderRepository) upsertOrders(ctx context.Context, orders []*entities.Order) ([]upsertOutcome, error) {
+	hashes := make([]string, len(orders))
+	seen := make(map[string]bool, len(orders))
+	for i, order := range orders {
+		// The batch would fail the same way on every attempt; the orders
+		// have to be stored one by one instead.
+		if seen[order.OrderUID] {
+			return nil, retry.Permanent(fmt.Errorf("%w: %s", errDuplicateInBatch, order.OrderUID))
+		}
+		seen[order.OrderUID] = true
+
+		hash, err := contentHash(order)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hash
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	inserted, err := insertOrderRows(ctx, tx, orders, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]upsertOutcome, len(orders))
+	var written []*entities.Order
+	var replaced []string
+	for i, order := range orders {
+		if inserted[order.OrderUID] {
+			outcomes[i] = outcomeInserted
+			written = append(written, order)
+			continue
+		}
+
+		outcomes[i], err = r.resolveConflict(ctx, tx, order, hashes[i])
+		if err != nil {
+			return nil, err
+		}
+		if outcomes[i] == outcomeOverwritten {
+			written = append(written, order)
+			replaced = append(replaced, order.OrderUID)
+		}
+	}
+
+	if len(replaced) > 0 {
+		if err := deleteOrdersChildren(ctx, tx, replaced); err != nil {
+			return nil, err
+		}
+	}
+	if err := insertOrdersChildren(ctx, tx, written); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return outcomes, nil
+}
+
+// insertOrderRows inserts the order rows and returns the uids that did not
+// exist yet.
+func insertOrderRows(ctx context.Context, tx *sql.Tx, orders []*entities.Order, hashes []string) (map[string]bool, error) {
+	rows := make([][]any, len(orders))
+	for i, o := range orders {
+		warnings, err := encodeWarnings(o)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = []any{o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID, o.DeliveryService, o.ShardKey, o.SmID, o.DateCreated, o.OofShard, hashes[i], warnings}
+	}
+
+	inserted := make(map[string]bool, len(orders))
+	err := execMultiRow(rows, func(values string, args []any) error {
+		query := `INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, content_hash, warnings) VALUES ` + values + ` ON CONFLICT (order_uid) DO NOTHING RETURNING order_uid`
+		res, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer res.Close()
+
+		for res.Next() {
+			var uid string
+			if err := res.Scan(&uid); err != nil {
+				return err
+			}
+			inserted[uid] = true
+		}
+		return res.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+func deleteOrdersChildren(ctx context.Context, tx *sql.Tx, orderUIDs []string) error {
+	for _, query := range []string{
+		`DELETE FROM delivery WHERE order_uid = ANY($1)`,
+		`DELETE FROM payment WHERE order_uid = ANY($1)`,
+		`DELETE FROM items WHERE order_uid = ANY($1)`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, orderUIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertOrdersChildren(ctx context.Context, tx *sql.Tx, orders []*entities.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	var deliveries, payments, items [][]any
+	for _, o := range orders {
+		d := o.Delivery
+		deliveries = append(deliveries, []any{o.OrderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email})
+
+		p := o.Payment
+		payments = append(payments, []any{o.OrderUID, p.Transaction, p.RequestID, p.Currency, p.Provider, p.Amount, p.PaymentDT, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee})
+
+		for _, it := range o.Items {
+			items = append(items, []any{o.OrderUID, it.ChrtID, it.TrackNumber, it.Price, it.Rid, it.Name, it.Sale, it.Size, it.TotalPrice, it.NmID, it.Brand, it.Status})
+		}
+	}
+
+	for _, stmt := range []struct {
+		prefix string
+		rows   [][]any
+	}{
+		{`INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email) VALUES `, deliveries},
+		{`INSERT INTO payment (order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) VALUES `, payments},
+		{`INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status) VALUES `, items},
+	} {
+		err := execMultiRow(stmt.rows, func(values string, args []any) error {
+			_, err := tx.ExecContext(ctx, stmt.prefix+values, args...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// execMultiRow splits rows into statements that stay within the parameter
+// limit and calls exec with the VALUES list and arguments of each.
+func execMultiRow(rows [][]any, exec func(values string, args []any) error) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	perStatement := maxStatementParams / len(rows[0])
+
+	for start := 0; start < len(rows); start += perStatement {
+		chunk := rows[start:min(start+perStatement, len(rows))]
+
+		var values strings.Builder
+		args := make([]any, 0, len(chunk)*len(chunk[0]))
+		for i, row := range chunk {
+			if i > 0 {
+				values.WriteString(", ")
+			}
+			values.WriteByte('(')
+			for j, v := range row {
+				if j > 0 {
+					values.WriteString(", ")
+				}
+				args = append(args, v)
+				fmt.Fprintf(&values, "$%d", len(args))
+			}
+			values.WriteByte(')')
+		}
+
+		if err := exec(values.String(), args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/retry"
+)
+
+func TestUpsertOrdersRejectsDuplicates(t *testing.T) {
+	// The duplicate is found before a transaction is started, so no
+	// database is needed.
+	r := &OrderRepository{}
+	orders := []*entities.Order{{OrderUID: "a"}, {OrderUID: "b"}, {OrderUID: "a"}}
+
+	_, err := r.upsertOrders(context.Background(), orders)
+	if !errors.Is(err, errDuplicateInBatch) {
+		t.Fatalf("err = %v, want %v", err, errDuplicateInBatch)
+	}
+	if !retry.IsPermanent(err) {
+		t.Error("duplicate in batch is retryable")
+	}
+	if !retry.IsPermanent(classifyError(err)) {
+		t.Error("duplicate in batch is retryable after classification")
+	}
+}
+
+func TestExecMultiRowValues(t *testing.T) {
+	var got string
+	var gotArgs []any
+	err := execMultiRow([][]any{{"a", 1}, {"b", 2}}, func(values string, args []any) error {
+		got, gotArgs = values, args
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "($1, $2), ($3, $4)"; got != want {
+		t.Errorf("values = %q, want %q", got, want)
+	}
+	if want := []any{"a", 1, "b", 2}; !slices.Equal(gotArgs, want) {
+		t.Errorf("args = %v, want %v", gotArgs, want)
+	}
+}
+
+func TestExecMultiRowSplitsAtParameterLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    int
+		columns int
+		want    []int
+	}{
+		{name: "no rows", rows: 0, columns: 13},
+		{name: "order rows that fit one statement", rows: 5041, columns: 13, want: []int{5041}},
+		{name: "one order row over the limit", rows: 5042, columns: 13, want: []int{5041, 1}},
+		{name: "item rows", rows: 12000, columns: 12, want: []int{5461, 5461, 1078}},
+		{name: "single column at the limit", rows: maxStatementParams, columns: 1, want: []int{maxStatementParams}},
+		{name: "single column over the limit", rows: maxStatementParams + 1, columns: 1, want: []int{maxStatementParams, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := make([][]any, tt.rows)
+			for i := range rows {
+				rows[i] = make([]any, tt.columns)
+				rows[i][0] = i
+			}
+
+			var got []int
+			next := 0
+			err := execMultiRow(rows, func(values string, args []any) error {
+				if len(args) > maxStatementParams {
+					t.Errorf("statement has %d parameters", len(args))
+				}
+				if !strings.HasPrefix(values, "($1,") && !strings.HasPrefix(values, "($1)") {
+					t.Errorf("placeholders do not start at $1: %.20q", values)
+				}
+				if last := fmt.Sprintf("$%d)", len(args)); !strings.HasSuffix(values, last) {
+					t.Errorf("placeholders do not end at %s", last)
+				}
+				for i := 0; i < len(args); i += tt.columns {
+					if args[i] != next {
+						t.Fatalf("row %v out of order, want %d", args[i], next)
+					}
+					next++
+				}
+				got = append(got, len(args)/tt.columns)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rows per statement = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecMultiRowStopsAtError(t *testing.T) {
+	errExec := errors.New("insert failed")
+	calls := 0
+	rows := make([][]any, maxStatementParams+1)
+	for i := range rows {
+		rows[i] = []any{i}
+	}
+	err := execMultiRow(rows, func(string, []any) error {
+		calls++
+		return errExec
+	})
+	if !errors.Is(err, errExec) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want %v after 1", err, calls, errExec)
+	}
+}
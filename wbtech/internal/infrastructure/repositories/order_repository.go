@@ -1,66 +1,118 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
 
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
 	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
 	"github.com/agl/wbtech/pkg/logger"
+	"github.com/agl/wbtech/pkg/retry"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/sync/singleflight"
 )
 
 type OrderRepository struct {
-	db    *sql.DB
-	cache map[string]*entities.Order
+	db             *sql.DB
+	cache          interfaces.OrderCache
+	notFound       interfaces.NegativeCache
+	invalidations  interfaces.InvalidationPublisher
+	loads          singleflight.Group
+	cfg            config.Orders
+	warmUp         config.WarmUp
+	conflictPolicy ConflictPolicy
+
+	// fills tracks the orders being loaded into the cache by GetOrderByID and
+	// whether a write has superseded the load since it started.
+	fillsMu sync.Mutex
+	fills   map[string]bool
+
+	// warmUps counts the running warm-ups and written records the orders
+	// stored since the first of them began; both are guarded by fillsMu.
+	warmUps int
+	written map[string]bool
 }
 
-func NewOrderRepository(db *sql.DB) *OrderRepository {
-	repo := &OrderRepository{
-		db:    db,
-		cache: make(map[string]*entities.Order),
+func NewOrderRepository(db *sql.DB, cfg config.Orders, warmUp config.WarmUp, cache interfaces.OrderCache, notFound interfaces.NegativeCache, invalidations interfaces.InvalidationPublisher) *OrderRepository {
+	return &OrderRepository{
+		db:             db,
+		cache:          cache,
+		notFound:       notFound,
+		invalidations:  invalidations,
+		fills:          make(map[string]bool),
+		cfg:            cfg,
+		warmUp:         warmUp,
+		conflictPolicy: ConflictPolicy(cfg.ConflictPolicy),
 	}
-	repo.loadAllOrdersToCache()
-	return repo
 }
 
-func (r *OrderRepository) loadAllOrdersToCache() {
-	query := `SELECT order_uid FROM orders`
-	rows, err := r.db.Query(query)
-	if err != nil {
-		logger.Log.Error("Failed to load orders for cache", "error", err)
-		return
+func (r *OrderRepository) GetOrderByID(ctx context.Context, orderUID string) (*entities.Order, error) {
+	if order, ok := r.cache.Get(orderUID); ok {
+		logger.Log.Info("Order found in cache", "order_uid", orderUID)
+		return order, nil
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var orderUID string
-		if err := rows.Scan(&orderUID); err != nil {
-			logger.Log.Error("Failed to scan order_uid for cache", "error", err)
-			continue
-		}
-		order, err := r.GetOrderByID(orderUID)
-		if err != nil || order == nil {
-			logger.Log.Error("Failed to load order for cache", "order_uid", orderUID, "error", err)
-			continue
-		}
-		r.cache[orderUID] = order
+	if r.notFound.Contains(orderUID) {
+		logger.Log.Debug("Order recently not found, skipping database", "order_uid", orderUID)
+		return nil, nil
 	}
-	logger.Log.Info("Order cache initialized", "count", len(r.cache))
-}
 
-func (r *OrderRepository) GetOrderByID(orderUID string) (*entities.Order, error) {
-	if order, ok := r.cache[orderUID]; ok {
-		logger.Log.Info("Order found in cache", "order_uid", orderUID)
+	// Concurrent misses for the same order share a single database load. The
+	// load is detached from the caller's cancellation so one client giving up
+	// does not fail the others waiting on it; it is bounded by the read timeout.
+	results := r.loads.DoChan(orderUID, func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.cfg.ReadTimeout)
+		defer cancel()
+
+		r.beginFill(orderUID)
+		order, err := r.loadOrder(loadCtx, orderUID)
+		r.endFill(orderUID, func() {
+			switch {
+			case err != nil:
+			case order == nil:
+				r.notFound.Add(orderUID)
+			default:
+				r.cache.Set(order)
+			}
+		})
+		return order, err
+	})
+
+	select {
+	case res := <-results:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		if res.Shared {
+			logger.Log.Debug("Order load shared with concurrent request", "order_uid", orderUID)
+		}
+		order, _ := res.Val.(*entities.Order)
 		return order, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	tx, err := r.db.Begin()
+func (r *OrderRepository) loadOrder(ctx context.Context, orderUID string) (*entities.Order, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
 		logger.Log.Error("Failed to begin transaction", "error", err)
 		return nil, err
 	}
+	defer tx.Rollback()
 
+	return r.selectOrder(ctx, tx, orderUID)
+}
+
+func (r *OrderRepository) selectOrder(ctx context.Context, tx *sql.Tx, orderUID string) (*entities.Order, error) {
 	var order entities.Order
-	queryOrder := `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard FROM orders WHERE order_uid = $1`
-	err = tx.QueryRow(queryOrder, orderUID).Scan(
+	var warnings []byte
+	queryOrder := `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, warnings FROM orders WHERE order_uid = $1`
+	err := tx.QueryRowContext(ctx, queryOrder, orderUID).Scan(
 		&order.OrderUID,
 		&order.TrackNumber,
 		&order.Entry,
@@ -72,6 +124,7 @@ func (r *OrderRepository) GetOrderByID(orderUID string) (*entities.Order, error)
 		&order.SmID,
 		&order.DateCreated,
 		&order.OofShard,
+		&warnings,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -82,9 +135,13 @@ func (r *OrderRepository) GetOrderByID(orderUID string) (*entities.Order, error)
 		logger.Log.Error("Failed to select order", "order_uid", orderUID, "error", err)
 		return nil, err
 	}
+	if err := decodeWarnings(warnings, &order); err != nil {
+		logger.Log.Error("Failed to decode order warnings", "order_uid", orderUID, "error", err)
+		return nil, err
+	}
 
 	queryDelivery := `SELECT name, phone, zip, city, address, region, email FROM delivery WHERE order_uid = $1`
-	err = tx.QueryRow(queryDelivery, orderUID).Scan(
+	err = tx.QueryRowContext(ctx, queryDelivery, orderUID).Scan(
 		&order.Delivery.Name,
 		&order.Delivery.Phone,
 		&order.Delivery.Zip,
@@ -103,7 +160,7 @@ func (r *OrderRepository) GetOrderByID(orderUID string) (*entities.Order, error)
 	}
 
 	queryPayment := `SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM payment WHERE order_uid = $1`
-	err = tx.QueryRow(queryPayment, orderUID).Scan(
+	err = tx.QueryRowContext(ctx, queryPayment, orderUID).Scan(
 		&order.Payment.Transaction,
 		&order.Payment.RequestID,
 		&order.Payment.Currency,
@@ -126,7 +183,7 @@ func (r *OrderRepository) GetOrderByID(orderUID string) (*entities.Order, error)
 	}
 
 	queryItems := `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM items WHERE order_uid = $1`
-	rows, err := tx.Query(queryItems, orderUID)
+	rows, err := tx.QueryContext(ctx, queryItems, orderUID)
 	if err != nil {
 		return nil, err
 	}
@@ -159,113 +216,128 @@ func (r *OrderRepository) GetOrderByID(orderUID string) (*entities.Order, error)
 
 	logger.Log.Info("Order retrieved successfully", "order_uid", order.OrderUID)
 
-	r.cache[orderUID] = &order
-
 	return &order, nil
 }
 
-func (r *OrderRepository) StoreOrder(mshChan chan []byte) error {
-	var order entities.Order
-	for msg := range mshChan {
-		if err := json.Unmarshal(msg, &order); err != nil {
-			logger.Log.Error("Failed to unmarshal order", "error", err)
-			return err
-		}
+func (r *OrderRepository) StoreOrder(ctx context.Context, order *entities.Order) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.WriteTimeout)
+	defer cancel()
 
-		tx, err := r.db.Begin()
-		if err != nil {
-			logger.Log.Error("Failed to begin transaction", "error", err)
-			return err
+	outcome, err := r.upsertOrder(ctx, order)
+	if err != nil {
+		if errors.Is(err, ErrOrderConflict) {
+			logger.Log.Error("Conflicting order rejected", "order_uid", order.OrderUID, "policy", r.conflictPolicy)
 		}
+		return classifyError(err)
+	}
 
-		queryOrder := `INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
-		_, err = tx.Exec(queryOrder,
-			&order.OrderUID,
-			&order.TrackNumber,
-			&order.Entry,
-			&order.Locale,
-			&order.InternalSignature,
-			&order.CustomerID,
-			&order.DeliveryService,
-			&order.ShardKey,
-			&order.SmID,
-			&order.DateCreated,
-			&order.OofShard,
-		)
-		if err != nil {
-			logger.Log.Error("Failed to insert order", "order_uid", order.OrderUID, "error", err)
-			tx.Rollback()
-			return err
-		}
+	r.applyOutcome(order, outcome)
+	return nil
+}
 
-		queryDelivery := `INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-		_, err = tx.Exec(queryDelivery,
-			&order.OrderUID,
-			&order.Delivery.Name,
-			&order.Delivery.Phone,
-			&order.Delivery.Zip,
-			&order.Delivery.City,
-			&order.Delivery.Address,
-			&order.Delivery.Region,
-			&order.Delivery.Email,
-		)
-		if err != nil {
-			logger.Log.Error("Failed to insert delivery", "order_uid", order.OrderUID, "error", err)
-			tx.Rollback()
-			return err
-		}
+// applyOutcome brings the caches in line with a committed write.
+func (r *OrderRepository) applyOutcome(order *entities.Order, outcome upsertOutcome) {
+	switch outcome {
+	case outcomeDuplicate:
+		logger.Log.Info("Duplicate order ignored", "order_uid", order.OrderUID)
+		return
+	case outcomeStale:
+		logger.Log.Info("Older version of order ignored", "order_uid", order.OrderUID)
+		return
+	}
 
-		queryPayment := `INSERT INTO payment (order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
-		_, err = tx.Exec(queryPayment,
-			&order.OrderUID,
-			&order.Payment.Transaction,
-			&order.Payment.RequestID,
-			&order.Payment.Currency,
-			&order.Payment.Provider,
-			&order.Payment.Amount,
-			&order.Payment.PaymentDT,
-			&order.Payment.Bank,
-			&order.Payment.DeliveryCost,
-			&order.Payment.GoodsTotal,
-			&order.Payment.CustomFee,
-		)
-		if err != nil {
-			logger.Log.Error("Failed to insert payment", "order_uid", order.OrderUID, "error", err)
-			tx.Rollback()
-			return err
-		}
+	r.fillsMu.Lock()
+	if _, ok := r.fills[order.OrderUID]; ok {
+		r.fills[order.OrderUID] = true
+	}
+	if r.warmUps > 0 {
+		r.written[order.OrderUID] = true
+	}
+	r.cache.Set(order)
+	r.notFound.Delete(order.OrderUID)
+	r.fillsMu.Unlock()
 
-		for _, item := range order.Items {
-			queryItem := `INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
-			_, err = tx.Exec(queryItem,
-				&order.OrderUID,
-				&item.ChrtID,
-				&item.TrackNumber,
-				&item.Price,
-				&item.Rid,
-				&item.Name,
-				&item.Sale,
-				&item.Size,
-				&item.TotalPrice,
-				&item.NmID,
-				&item.Brand,
-				&item.Status,
-			)
-			if err != nil {
-				logger.Log.Error("Failed to insert item", "order_uid", order.OrderUID, "chrt_id", item.ChrtID, "error", err)
-				tx.Rollback()
-				return err
-			}
-		}
-		if err = tx.Commit(); err != nil {
-			logger.Log.Error("Failed to commit transaction", "error", err)
-			tx.Rollback()
-			return err
+	r.invalidations.PublishInvalidation(order.OrderUID, dto.InvalidationStored)
+	if outcome == outcomeOverwritten {
+		logger.Log.Info("Order overwritten successfully", "order_uid", order.OrderUID)
+	} else {
+		logger.Log.Info("Order stored successfully", "order_uid", order.OrderUID)
+	}
+}
+
+func (r *OrderRepository) beginFill(orderUID string) {
+	r.fillsMu.Lock()
+	defer r.fillsMu.Unlock()
+
+	r.fills[orderUID] = false
+}
+
+// endFill runs fill unless a write was applied while the order was loading;
+// the loaded result may then predate the write and must not replace it.
+func (r *OrderRepository) endFill(orderUID string, fill func()) {
+	r.fillsMu.Lock()
+	defer r.fillsMu.Unlock()
+
+	superseded := r.fills[orderUID]
+	delete(r.fills, orderUID)
+	if superseded {
+		logger.Log.Debug("Order changed while loading, not caching the load", "order_uid", orderUID)
+		return
+	}
+	fill()
+}
+
+func (r *OrderRepository) beginWarmUp() {
+	r.fillsMu.Lock()
+	defer r.fillsMu.Unlock()
+
+	if r.warmUps == 0 {
+		r.written = make(map[string]bool)
+	}
+	r.warmUps++
+}
+
+func (r *OrderRepository) endWarmUp() {
+	r.fillsMu.Lock()
+	defer r.fillsMu.Unlock()
+
+	r.warmUps--
+	if r.warmUps == 0 {
+		r.written = nil
+	}
+}
+
+// fillWarmUp caches orders read by a warm-up, leaving out those stored since
+// it began: the cache already holds the newer version and the rows read may
+// predate it.
+func (r *OrderRepository) fillWarmUp(orders []*entities.Order) {
+	r.fillsMu.Lock()
+	defer r.fillsMu.Unlock()
+
+	for _, order := range orders {
+		if r.written[order.OrderUID] {
+			logger.Log.Debug("Order changed during warm-up, not caching the load", "order_uid", order.OrderUID)
+			continue
 		}
+		r.cache.Set(order)
+	}
+}
 
-		r.cache[order.OrderUID] = &order
-		logger.Log.Info("Order stored successfully", "order_uid", order.OrderUID)
+// classifyError marks errors that will fail the same way on every attempt as
+// permanent: conflicts rejected by policy, invalid data and constraint
+// violations. Everything else, such as lost connections, deadlocks and
+// serialization failures, is left retryable.
+func classifyError(err error) error {
+	if errors.Is(err, ErrOrderConflict) {
+		return retry.Permanent(err)
 	}
 
-	return nil
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23") {
+			return retry.Permanent(err)
+		}
+	}
+
+	return err
 }
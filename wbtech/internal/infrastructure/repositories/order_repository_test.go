@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/agl/wbtech/pkg/retry"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{name: "conflict rejected by policy", err: fmt.Errorf("upsert: %w", ErrOrderConflict), permanent: true},
+		{name: "invalid text representation", err: &pgconn.PgError{Code: "22P02"}, permanent: true},
+		{name: "numeric value out of range", err: &pgconn.PgError{Code: "22003"}, permanent: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, permanent: true},
+		{name: "foreign key violation", err: fmt.Errorf("insert items: %w", &pgconn.PgError{Code: "23503"}), permanent: true},
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}},
+		{name: "timeout", err: context.DeadlineExceeded},
+		{name: "connection lost", err: errors.New("unexpected EOF")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			if retry.IsPermanent(err) != tt.permanent {
+				t.Errorf("permanent = %v, want %v", retry.IsPermanent(err), tt.permanent)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("classified error %v does not wrap %v", err, tt.err)
+			}
+		})
+	}
+}
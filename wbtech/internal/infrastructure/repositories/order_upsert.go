@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+type ConflictPolicy string
+
+const (
+	ConflictReject     ConflictPolicy = "reject"
+	ConflictOverwrite  ConflictPolicy = "overwrite"
+	ConflictKeepNewest ConflictPolicy = "keep-newest"
+)
+
+var ErrOrderConflict = errors.New("order already exists with different content")
+
+type upsertOutcome int
+
+const (
+	outcomeInserted upsertOutcome = iota
+	outcomeOverwritten
+	outcomeDuplicate
+	outcomeStale
+)
+
+// contentHash leaves out the warnings: they depend on the invariant
+// configuration rather than on the order.
+func contentHash(order *entities.Order) (string, error) {
+	content := *order
+	content.Warnings = nil
+	b, err := json.Marshal(&content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// encodeWarnings returns the value of the warnings column, NULL when the order
+// has none.
+func encodeWarnings(order *entities.Order) (any, error) {
+	if len(order.Warnings) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(order.Warnings)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func decodeWarnings(raw []byte, order *entities.Order) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, &order.Warnings)
+}
+
+// upsertOrder stores the order in a single transaction. A redelivered order with
+// identical content is a no-op; an order whose content differs from the stored
+// one is resolved by the configured conflict policy, replacing the delivery,
+// payment and items rows together with the order row.
+func (r *OrderRepository) upsertOrder(ctx context.Context, order *entities.Order) (upsertOutcome, error) {
+	hash, err := contentHash(order)
+	if err != nil {
+		return 0, err
+	}
+	warnings, err := encodeWarnings(order)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Log.Error("Failed to begin transaction", "error", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	queryOrder := `INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, content_hash, warnings) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) ON CONFLICT (order_uid) DO NOTHING`
+	res, err := tx.ExecContext(ctx, queryOrder,
+		&order.OrderUID,
+		&order.TrackNumber,
+		&order.Entry,
+		&order.Locale,
+		&order.InternalSignature,
+		&order.CustomerID,
+		&order.DeliveryService,
+		&order.ShardKey,
+		&order.SmID,
+		&order.DateCreated,
+		&order.OofShard,
+		hash,
+		warnings,
+	)
+	if err != nil {
+		logger.Log.Error("Failed to insert order", "order_uid", order.OrderUID, "error", err)
+		return 0, err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	outcome := outcomeInserted
+	if inserted == 0 {
+		outcome, err = r.resolveConflict(ctx, tx, order, hash)
+		if err != nil {
+			return 0, err
+		}
+		if outcome == outcomeDuplicate || outcome == outcomeStale {
+			return outcome, nil
+		}
+		if err := deleteOrderChildren(ctx, tx, order.OrderUID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := insertOrderChildren(ctx, tx, order); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Log.Error("Failed to commit transaction", "error", err)
+		return 0, err
+	}
+
+	return outcome, nil
+}
+
+func (r *OrderRepository) resolveConflict(ctx context.Context, tx *sql.Tx, order *entities.Order, hash string) (upsertOutcome, error) {
+	var existingHash sql.NullString
+	err := tx.QueryRowContext(ctx, `SELECT content_hash FROM orders WHERE order_uid = $1 FOR UPDATE`, order.OrderUID).Scan(&existingHash)
+	if err != nil {
+		logger.Log.Error("Failed to lock existing order", "order_uid", order.OrderUID, "error", err)
+		return 0, err
+	}
+
+	// Orders stored before content hashes were recorded are compared by
+	// reloading them.
+	if !existingHash.Valid {
+		existing, err := r.selectOrder(ctx, tx, order.OrderUID)
+		if err != nil {
+			return 0, err
+		}
+		if existing != nil {
+			if existingHash.String, err = contentHash(existing); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if existingHash.String == hash {
+		return outcomeDuplicate, nil
+	}
+
+	queryUpdate := `UPDATE orders SET track_number = $2, entry = $3, locale = $4, internal_signature = $5, customer_id = $6, delivery_service = $7, shardkey = $8, sm_id = $9, date_created = $10, oof_shard = $11, content_hash = $12, warnings = $13, updated_at = now() WHERE order_uid = $1`
+	switch r.conflictPolicy {
+	case ConflictOverwrite:
+	case ConflictKeepNewest:
+		queryUpdate += ` AND (date_created IS NULL OR date_created < $10)`
+	default:
+		return 0, ErrOrderConflict
+	}
+
+	warnings, err := encodeWarnings(order)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, queryUpdate,
+		&order.OrderUID,
+		&order.TrackNumber,
+		&order.Entry,
+		&order.Locale,
+		&order.InternalSignature,
+		&order.CustomerID,
+		&order.DeliveryService,
+		&order.ShardKey,
+		&order.SmID,
+		&order.DateCreated,
+		&order.OofShard,
+		hash,
+		warnings,
+	)
+	if err != nil {
+		logger.Log.Error("Failed to update order", "order_uid", order.OrderUID, "error", err)
+		return 0, err
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if updated == 0 {
+		return outcomeStale, nil
+	}
+
+	return outcomeOverwritten, nil
+}
+
+func deleteOrderChildren(ctx context.Context, tx *sql.Tx, orderUID string) error {
+	for _, query := range []string{
+		`DELETE FROM delivery WHERE order_uid = $1`,
+		`DELETE FROM payment WHERE order_uid = $1`,
+		`DELETE FROM items WHERE order_uid = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, orderUID); err != nil {
+			logger.Log.Error("Failed to delete order child rows", "order_uid", orderUID, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func insertOrderChildren(ctx context.Context, tx *sql.Tx, order *entities.Order) error {
+	queryDelivery := `INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := tx.ExecContext(ctx, queryDelivery,
+		&order.OrderUID,
+		&order.Delivery.Name,
+		&order.Delivery.Phone,
+		&order.Delivery.Zip,
+		&order.Delivery.City,
+		&order.Delivery.Address,
+		&order.Delivery.Region,
+		&order.Delivery.Email,
+	)
+	if err != nil {
+		logger.Log.Error("Failed to insert delivery", "order_uid", order.OrderUID, "error", err)
+		return err
+	}
+
+	queryPayment := `INSERT INTO payment (order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err = tx.ExecContext(ctx, queryPayment,
+		&order.OrderUID,
+		&order.Payment.Transaction,
+		&order.Payment.RequestID,
+		&order.Payment.Currency,
+		&order.Payment.Provider,
+		&order.Payment.Amount,
+		&order.Payment.PaymentDT,
+		&order.Payment.Bank,
+		&order.Payment.DeliveryCost,
+		&order.Payment.GoodsTotal,
+		&order.Payment.CustomFee,
+	)
+	if err != nil {
+		logger.Log.Error("Failed to insert payment", "order_uid", order.OrderUID, "error", err)
+		return err
+	}
+
+	for _, item := range order.Items {
+		queryItem := `INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+		_, err = tx.ExecContext(ctx, queryItem,
+			&order.OrderUID,
+			&item.ChrtID,
+			&item.TrackNumber,
+			&item.Price,
+			&item.Rid,
+			&item.Name,
+			&item.Sale,
+			&item.Size,
+			&item.TotalPrice,
+			&item.NmID,
+			&item.Brand,
+			&item.Status,
+		)
+		if err != nil {
+			logger.Log.Error("Failed to insert item", "order_uid", order.OrderUID, "chrt_id", item.ChrtID, "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+)
+
+func TestWarningsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		warnings []entities.Warning
+		wantNull bool
+	}{
+		{name: "no warnings are stored as NULL", wantNull: true},
+		{name: "empty warnings are stored as NULL", warnings: []entities.Warning{}, wantNull: true},
+		{
+			name: "warnings are stored as JSON",
+			warnings: []entities.Warning{
+				{Path: "$.payment.amount", Rule: "payment_amount", Message: "amount 0 does not match 1917"},
+				{Path: "$.items[1].track_number", Rule: "item_track_number", Message: "track number differs"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := encodeWarnings(&entities.Order{Warnings: tt.warnings})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (value == nil) != tt.wantNull {
+				t.Fatalf("column value = %v, want NULL = %v", value, tt.wantNull)
+			}
+
+			var raw []byte
+			if value != nil {
+				raw = []byte(value.(string))
+			}
+			var order entities.Order
+			if err := decodeWarnings(raw, &order); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(order.Warnings, tt.warnings) {
+				t.Errorf("decoded warnings = %v, want %v", order.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestContentHashIgnoresWarnings(t *testing.T) {
+	order := &entities.Order{OrderUID: "a", Payment: entities.Payment{Amount: 100}}
+	want, err := contentHash(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order.Warnings = []entities.Warning{{Path: "$.payment.amount", Rule: "payment_amount"}}
+	if got, _ := contentHash(order); got != want {
+		t.Error("warnings changed the content hash")
+	}
+	if len(order.Warnings) != 1 {
+		t.Error("contentHash cleared the order's warnings")
+	}
+
+	order.Payment.Amount = 200
+	if got, _ := contentHash(order); got == want {
+		t.Error("a different amount kept the content hash")
+	}
+}
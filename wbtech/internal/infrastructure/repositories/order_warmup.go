@@ -0,0 +1,310 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/agl/wbtech/pkg/logger"
+)
+
+// Watermark is taken from the data rather than the clock. updated_at is the
+// start time of the writing transaction, so a transaction that is still open
+// commits rows older than max(updated_at); the watermark goes back to the
+// start of the oldest open transaction to cover them. It is zero for an empty
+// table.
+func (r *OrderRepository) Watermark(ctx context.Context) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.ReadTimeout)
+	defer cancel()
+
+	var watermark sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT LEAST(
+		(SELECT max(updated_at) FROM orders),
+		(SELECT min(xact_start) FROM pg_stat_activity WHERE datname = current_database() AND pid <> pg_backend_pid())
+	)`).Scan(&watermark)
+	return watermark.Time, err
+}
+
+func (r *OrderRepository) ExistingOrderUIDs(ctx context.Context, uids []string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.ReadTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT order_uid FROM orders WHERE order_uid = ANY($1)`, uids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool, len(uids))
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		existing[uid] = true
+	}
+	return existing, rows.Err()
+}
+
+type orderCursor struct {
+	sortKey  time.Time
+	orderUID string
+}
+
+// WarmUpCache fills the cache with the most recent orders first. Order keys are
+// paged by (date_created, order_uid) and every page is read by workers in one
+// snapshot with one set-based query per table. Orders stored while the warm-up
+// runs are not overwritten by it. A non-zero updatedSince restricts the load to
+// orders written after that moment.
+func (r *OrderRepository) WarmUpCache(ctx context.Context, updatedSince time.Time) {
+	cfg := r.warmUp
+	start := time.Now()
+	logger.Log.Info("Starting cache warm-up", "limit", cfg.Limit, "days", cfg.Days, "updated_since", updatedSince, "chunk_size", cfg.ChunkSize, "workers", cfg.Workers)
+
+	r.beginWarmUp()
+	defer r.endWarmUp()
+
+	chunks := make(chan []string, cfg.Workers)
+	var loaded, failed atomic.Int64
+	var wg sync.WaitGroup
+
+	for range cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				orders, err := r.loadOrderPage(ctx, chunk)
+				if err != nil {
+					failed.Add(int64(len(chunk)))
+					logger.Log.Error("Failed to load order details for cache", "first_order_uid", chunk[0], "count", len(chunk), "error", err)
+					continue
+				}
+				r.fillWarmUp(orders)
+				total := loaded.Add(int64(len(orders)))
+				logger.Log.Info("Cache warm-up progress", "loaded", total, "elapsed", time.Since(start))
+			}
+		}()
+	}
+
+	err := r.pageOrders(ctx, cfg, updatedSince, func(chunk []string) {
+		chunks <- chunk
+	})
+	close(chunks)
+	wg.Wait()
+
+	if err != nil {
+		logger.Log.Error("Cache warm-up stopped early", "error", err)
+	}
+	logger.Log.Info("Order cache initialized", "loaded", loaded.Load(), "failed", failed.Load(), "count", r.cache.Len(), "elapsed", time.Since(start))
+}
+
+// pageOrders emits the uids of the orders to load, page by page. The rows
+// themselves are read later, in one snapshot per page.
+func (r *OrderRepository) pageOrders(ctx context.Context, cfg config.WarmUp, updatedSince time.Time, emit func([]string)) error {
+	var cursor *orderCursor
+	var since time.Time
+	if cfg.Days > 0 {
+		since = time.Now().AddDate(0, 0, -cfg.Days)
+	}
+
+	remaining := cfg.Limit
+	for cfg.Limit <= 0 || remaining > 0 {
+		size := cfg.ChunkSize
+		if cfg.Limit > 0 && remaining < size {
+			size = remaining
+		}
+
+		chunk, next, err := r.selectOrderPage(ctx, since, updatedSince, cursor, size)
+		if err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		emit(chunk)
+		remaining -= len(chunk)
+		cursor = next
+
+		if len(chunk) < size {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *OrderRepository) selectOrderPage(ctx context.Context, since, updatedSince time.Time, cursor *orderCursor, size int) ([]string, *orderCursor, error) {
+	var conds []string
+	var args []any
+	if !since.IsZero() {
+		args = append(args, since)
+		conds = append(conds, fmt.Sprintf("date_created >= $%d", len(args)))
+	}
+	if !updatedSince.IsZero() {
+		args = append(args, updatedSince)
+		conds = append(conds, fmt.Sprintf("updated_at >= $%d", len(args)))
+	}
+	if cursor != nil {
+		args = append(args, cursor.sortKey, cursor.orderUID)
+		conds = append(conds, fmt.Sprintf("(COALESCE(date_created, 'epoch'::timestamp), order_uid) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+	args = append(args, size)
+
+	query := `SELECT order_uid, COALESCE(date_created, 'epoch'::timestamp) FROM orders`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY COALESCE(date_created, 'epoch'::timestamp) DESC, order_uid DESC LIMIT $%d", len(args))
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.ReadTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var chunk []string
+	next := &orderCursor{}
+	for rows.Next() {
+		if err := rows.Scan(&next.orderUID, &next.sortKey); err != nil {
+			return nil, nil, err
+		}
+		chunk = append(chunk, next.orderUID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return chunk, next, nil
+}
+
+// loadOrderPage reads the orders and their delivery, payment and items in a
+// single snapshot, so every order comes from one committed version. Orders
+// deleted since the page was selected are left out.
+func (r *OrderRepository) loadOrderPage(ctx context.Context, uids []string) ([]*entities.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.ReadTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	byUID := make(map[string]*entities.Order, len(uids))
+	rows, err := tx.QueryContext(ctx, `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, warnings FROM orders WHERE order_uid = ANY($1)`, uids)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var order entities.Order
+		var warnings []byte
+		err := rows.Scan(
+			&order.OrderUID,
+			&order.TrackNumber,
+			&order.Entry,
+			&order.Locale,
+			&order.InternalSignature,
+			&order.CustomerID,
+			&order.DeliveryService,
+			&order.ShardKey,
+			&order.SmID,
+			&order.DateCreated,
+			&order.OofShard,
+			&warnings,
+		)
+		if err == nil {
+			err = decodeWarnings(warnings, &order)
+		}
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byUID[order.OrderUID] = &order
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasDelivery := make(map[string]bool, len(uids))
+	rows, err = tx.QueryContext(ctx, `SELECT order_uid, name, phone, zip, city, address, region, email FROM delivery WHERE order_uid = ANY($1)`, uids)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var uid string
+		var d entities.Delivery
+		if err := rows.Scan(&uid, &d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byUID[uid].Delivery = d
+		hasDelivery[uid] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasPayment := make(map[string]bool, len(uids))
+	rows, err = tx.QueryContext(ctx, `SELECT order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM payment WHERE order_uid = ANY($1)`, uids)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var uid string
+		var p entities.Payment
+		if err := rows.Scan(&uid, &p.Transaction, &p.RequestID, &p.Currency, &p.Provider, &p.Amount, &p.PaymentDT, &p.Bank, &p.DeliveryCost, &p.GoodsTotal, &p.CustomFee); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byUID[uid].Payment = p
+		hasPayment[uid] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM items WHERE order_uid = ANY($1) ORDER BY id`, uids)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var uid string
+		var item entities.Item
+		if err := rows.Scan(&uid, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byUID[uid].Items = append(byUID[uid].Items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	orders := make([]*entities.Order, 0, len(uids))
+	for _, uid := range uids {
+		order, ok := byUID[uid]
+		if !ok {
+			continue
+		}
+		if !hasDelivery[uid] || !hasPayment[uid] {
+			logger.Log.Warn("Skipping incomplete order during cache warm-up", "order_uid", uid)
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/domain/entities"
+	"github.com/agl/wbtech/internal/infrastructure/cache"
+	"github.com/agl/wbtech/pkg/config"
+)
+
+type nopInvalidations struct{}
+
+func (nopInvalidations) PublishInvalidation(orderUID, reason string) {}
+func (nopInvalidations) Stats() dto.InvalidationStats                { return dto.InvalidationStats{} }
+
+func TestWarmUpKeepsOrdersStoredMeanwhile(t *testing.T) {
+	orders := cache.NewMemoryCache(config.Cache{})
+	r := NewOrderRepository(nil, config.Orders{}, config.WarmUp{}, orders, cache.NewNegativeCache(time.Minute, 10), nopInvalidations{})
+
+	stale := func(uid string) *entities.Order { return &entities.Order{OrderUID: uid, Locale: "stale"} }
+
+	r.beginWarmUp()
+	r.applyOutcome(&entities.Order{OrderUID: "a", Locale: "fresh"}, outcomeInserted)
+	r.fillWarmUp([]*entities.Order{stale("a"), stale("b")})
+	r.endWarmUp()
+
+	if o, _ := orders.Get("a"); o == nil || o.Locale != "fresh" {
+		t.Errorf("order stored during the warm-up = %+v, want the fresh version", o)
+	}
+	if _, ok := orders.Get("b"); !ok {
+		t.Error("untouched order not cached by the warm-up")
+	}
+
+	// A later warm-up no longer remembers writes from before it began.
+	r.beginWarmUp()
+	r.fillWarmUp([]*entities.Order{stale("a")})
+	r.endWarmUp()
+	if o, _ := orders.Get("a"); o == nil || o.Locale != "stale" {
+		t.Errorf("order = %+v, want the version read by the second warm-up", o)
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/agl/wbtech/internal/application/services"
+)
+
+func (oc *OrderController) registerAdminRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/cache/stats", oc.admin(oc.getCacheStats))
+	mux.HandleFunc("DELETE /admin/cache/orders/{id}", oc.admin(oc.evictOrder))
+	mux.HandleFunc("POST /admin/cache/flush", oc.admin(oc.flushCache))
+	mux.HandleFunc("POST /admin/cache/rewarm", oc.admin(oc.rewarmCache))
+}
+
+// admin requires the admin bearer token. Without a configured token the admin
+// endpoints are disabled rather than open.
+func (oc *OrderController) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if oc.adminToken == "" {
+			http.Error(w, "admin endpoints are disabled", http.StatusForbidden)
+			return
+		}
+		token := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(token), []byte("Bearer "+oc.adminToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (oc *OrderController) getCacheStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oc.cacheService.Stats())
+}
+
+func (oc *OrderController) evictOrder(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	oc.cacheService.Evict(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (oc *OrderController) flushCache(w http.ResponseWriter, r *http.Request) {
+	oc.cacheService.Flush()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (oc *OrderController) rewarmCache(w http.ResponseWriter, r *http.Request) {
+	if err := oc.cacheService.Rewarm(); err != nil {
+		if errors.Is(err, services.ErrWarmUpInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "warming"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
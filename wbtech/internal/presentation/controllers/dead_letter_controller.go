@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/agl/wbtech/internal/application/dto"
+)
+
+func (oc *OrderController) registerDeadLetterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/dlq", oc.admin(oc.listDeadLetters))
+	mux.HandleFunc("GET /admin/dlq/{partition}/{offset}", oc.admin(oc.getDeadLetter))
+	mux.HandleFunc("POST /admin/dlq/{partition}/{offset}/replay", oc.admin(oc.replayDeadLetter))
+	mux.HandleFunc("POST /admin/dlq/replay", oc.admin(oc.replayDeadLetters))
+}
+
+func (oc *OrderController) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	partition := int64(-1)
+	if v := query.Get("partition"); v != "" {
+		p, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid partition", http.StatusBadRequest)
+			return
+		}
+		partition = p
+	}
+	var from int64
+	if v := query.Get("from"); v != "" {
+		o, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid from offset", http.StatusBadRequest)
+			return
+		}
+		from = o
+	}
+	var limit int
+	if v := query.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	letters, err := oc.deadLetterService.List(int32(partition), from, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if letters == nil {
+		letters = []dto.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, letters)
+}
+
+func (oc *OrderController) getDeadLetter(w http.ResponseWriter, r *http.Request) {
+	partition, offset, ok := deadLetterPosition(w, r)
+	if !ok {
+		return
+	}
+
+	letter, err := oc.deadLetterService.Get(partition, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if letter == nil {
+		http.Error(w, "dead letter not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, letter)
+}
+
+func (oc *OrderController) replayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	partition, offset, ok := deadLetterPosition(w, r)
+	if !ok {
+		return
+	}
+
+	// An empty body, chunked or not, replays the original payload.
+	req := dto.DeadLetterReplay{Partition: partition, Offset: offset}
+	var payload json.RawMessage
+	switch err := json.NewDecoder(r.Body).Decode(&payload); {
+	case err == nil:
+		req.Payload = payload
+	case !errors.Is(err, io.EOF):
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	result := oc.deadLetterService.Replay([]dto.DeadLetterReplay{req})[0]
+	if result.Error != "" {
+		writeJSON(w, http.StatusBadGateway, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (oc *OrderController) replayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Messages []dto.DeadLetterReplay `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Messages) == 0 {
+		http.Error(w, "messages are required", http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, oc.deadLetterService.Replay(body.Messages))
+}
+
+func deadLetterPosition(w http.ResponseWriter, r *http.Request) (int32, int64, bool) {
+	partition, err := strconv.ParseInt(r.PathValue("partition"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid partition", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	offset, err := strconv.ParseInt(r.PathValue("offset"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return int32(partition), offset, true
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agl/wbtech/internal/application/dto"
+)
+
+type fakeDeadLetterService struct {
+	replayed []dto.DeadLetterReplay
+}
+
+func (s *fakeDeadLetterService) List(partition int32, from int64, limit int) ([]dto.DeadLetter, error) {
+	return nil, nil
+}
+
+func (s *fakeDeadLetterService) Get(partition int32, offset int64) (*dto.DeadLetter, error) {
+	return nil, nil
+}
+
+func (s *fakeDeadLetterService) Replay(requests []dto.DeadLetterReplay) []dto.DeadLetterReplayResult {
+	s.replayed = append(s.replayed, requests...)
+	return make([]dto.DeadLetterReplayResult, len(requests))
+}
+
+func TestReplayDeadLetterBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        io.Reader
+		chunked     bool
+		wantStatus  int
+		wantPayload string
+	}{
+		{name: "no body", body: http.NoBody, wantStatus: http.StatusOK},
+		{name: "empty body", body: strings.NewReader(""), wantStatus: http.StatusOK},
+		{name: "empty chunked body", body: strings.NewReader(""), chunked: true, wantStatus: http.StatusOK},
+		{name: "whitespace only", body: strings.NewReader(" \n"), chunked: true, wantStatus: http.StatusOK},
+		{name: "replacement payload", body: strings.NewReader(`{"order_uid":"a"}`), wantStatus: http.StatusOK, wantPayload: `{"order_uid":"a"}`},
+		{name: "chunked replacement payload", body: strings.NewReader(`{"order_uid":"a"}`), chunked: true, wantStatus: http.StatusOK, wantPayload: `{"order_uid":"a"}`},
+		{name: "invalid payload", body: strings.NewReader(`{"order_uid"`), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeDeadLetterService{}
+			oc := &OrderController{deadLetterService: service}
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/dlq/1/42/replay", tt.body)
+			r.SetPathValue("partition", "1")
+			r.SetPathValue("offset", "42")
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			oc.replayDeadLetter(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(service.replayed) != 0 {
+					t.Error("invalid request was replayed")
+				}
+				return
+			}
+			if len(service.replayed) != 1 {
+				t.Fatalf("replayed %d requests, want 1", len(service.replayed))
+			}
+			req := service.replayed[0]
+			if req.Partition != 1 || req.Offset != 42 {
+				t.Errorf("replayed %d/%d, want 1/42", req.Partition, req.Offset)
+			}
+			if string(req.Payload) != tt.wantPayload {
+				t.Errorf("payload = %q, want %q", req.Payload, tt.wantPayload)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/agl/wbtech/internal/application/dto"
+	"github.com/agl/wbtech/internal/application/interfaces"
+)
+
+func (oc *OrderController) registerMonitoringRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /health", oc.health)
+	mux.HandleFunc("GET /ready", oc.ready)
+	mux.HandleFunc("GET /metrics", oc.metrics)
+	mux.HandleFunc("GET /admin/consumer/stats", oc.admin(oc.getConsumerStats))
+	mux.HandleFunc("GET /admin/consumer/state", oc.admin(oc.getConsumerState))
+	mux.HandleFunc("POST /admin/consumer/pause", oc.admin(oc.pauseConsumer))
+	mux.HandleFunc("POST /admin/consumer/resume", oc.admin(oc.resumeConsumer))
+}
+
+func (oc *OrderController) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (oc *OrderController) ready(w http.ResponseWriter, r *http.Request) {
+	if err := oc.monitor.Ready(); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "reason": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (oc *OrderController) getConsumerStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oc.monitor.Stats())
+}
+
+func (oc *OrderController) getConsumerState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oc.ingestion.PauseState())
+}
+
+func (oc *OrderController) pauseConsumer(w http.ResponseWriter, r *http.Request) {
+	oc.ingestion.Pause(interfaces.PauseReasonAdmin)
+	writeJSON(w, http.StatusOK, oc.ingestion.PauseState())
+}
+
+// resumeConsumer only lifts the admin pause; the consumer stays paused while
+// the database is unreachable, which the returned state shows.
+func (oc *OrderController) resumeConsumer(w http.ResponseWriter, r *http.Request) {
+	oc.ingestion.Resume(interfaces.PauseReasonAdmin)
+	writeJSON(w, http.StatusOK, oc.ingestion.PauseState())
+}
+
+// metrics renders consumer and cache statistics in the Prometheus text format.
+func (oc *OrderController) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	consumer := oc.monitor.Stats()
+	writeMetric(w, "order_consumer_lag", "gauge", "Messages between the committed offset and the high-water mark.")
+	for _, p := range consumer.Partitions {
+		fmt.Fprintf(w, "order_consumer_lag{topic=%q,partition=\"%d\"} %d\n", p.Topic, p.Partition, p.Lag)
+	}
+	writeMetric(w, "order_consumer_high_water_mark", "gauge", "Next offset to be written to the partition.")
+	for _, p := range consumer.Partitions {
+		fmt.Fprintf(w, "order_consumer_high_water_mark{topic=%q,partition=\"%d\"} %d\n", p.Topic, p.Partition, p.HighWaterMark)
+	}
+	writeMetric(w, "order_consumer_committed_offset", "gauge", "Offset committed by the consumer group.")
+	for _, p := range consumer.Partitions {
+		fmt.Fprintf(w, "order_consumer_committed_offset{topic=%q,partition=\"%d\"} %d\n", p.Topic, p.Partition, p.Committed)
+	}
+	writeValue(w, "order_consumer_lag_total", "gauge", "Total lag over all partitions.", float64(consumer.TotalLag))
+	writeValue(w, "order_messages_processed_total", "counter", "Messages stored successfully.", float64(consumer.Processed))
+	writeValue(w, "order_messages_failed_total", "counter", "Messages that could not be stored.", float64(consumer.Failed))
+	writeValue(w, "order_messages_per_second", "gauge", "Messages handled per second over the last interval.", consumer.MessagesPerSecond)
+	writeValue(w, "order_processing_latency_avg_ms", "gauge", "Average processing latency over the last interval.", consumer.AvgLatencyMs)
+	writeValue(w, "order_processing_latency_max_ms", "gauge", "Maximum processing latency over the last interval.", consumer.MaxLatencyMs)
+	writeValue(w, "order_consumer_healthy", "gauge", "1 while the lag is within the threshold.", boolValue(consumer.Healthy))
+	writeValue(w, "order_consumer_paused", "gauge", "1 while ingestion is paused.", boolValue(oc.ingestion.PauseState().Paused))
+
+	writeCacheMetrics(w, oc.cacheService.Stats())
+}
+
+func writeCacheMetrics(w io.Writer, stats dto.CacheStats) {
+	writeValue(w, "order_cache_hits_total", "counter", "Cache hits.", float64(stats.Hits))
+	writeValue(w, "order_cache_misses_total", "counter", "Cache misses.", float64(stats.Misses))
+	writeValue(w, "order_cache_evictions_total", "counter", "Entries evicted by the cache policy.", float64(stats.Evictions))
+	writeValue(w, "order_cache_entries", "gauge", "Entries in the cache.", float64(stats.Entries))
+	writeValue(w, "order_cache_bytes", "gauge", "Approximate size of the cached orders.", float64(stats.ApproxBytes))
+	if inv := stats.Invalidations; inv != nil {
+		writeValue(w, "order_cache_invalidations_published_total", "counter", "Cache invalidations sent to other replicas.", float64(inv.Published))
+		writeValue(w, "order_cache_invalidations_failed_total", "counter", "Cache invalidations that could not be sent.", float64(inv.Failed))
+		writeValue(w, "order_cache_invalidations_dropped_total", "counter", "Cache invalidations dropped because the queue was full.", float64(inv.Dropped))
+		writeValue(w, "order_cache_invalidations_queued", "gauge", "Cache invalidations waiting to be sent.", float64(inv.Queued))
+	}
+}
+
+func writeMetric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeValue(w io.Writer, name, kind, help string, v float64) {
+	writeMetric(w, name, kind, help)
+	fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,42 +1,74 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/agl/wbtech/internal/application/interfaces"
+	"github.com/agl/wbtech/pkg/config"
 	"github.com/agl/wbtech/pkg/logger"
 )
 
 type OrderController struct {
-	port    string
-	service interfaces.OrderService
+	server            *http.Server
+	port              int
+	adminToken        string
+	service           interfaces.OrderService
+	cacheService      interfaces.CacheService
+	deadLetterService interfaces.DeadLetterService
+	monitor           interfaces.ConsumerMonitor
+	ingestion         interfaces.IngestionControl
 }
 
-func NewOrderController(service interfaces.OrderService) *OrderController {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// NewOrderController builds the HTTP server up front, so Shutdown stops it even
+// when called before StartServer has begun listening.
+func NewOrderController(cfg config.HTTP, service interfaces.OrderService, cacheService interfaces.CacheService, deadLetterService interfaces.DeadLetterService, monitor interfaces.ConsumerMonitor, ingestion interfaces.IngestionControl) *OrderController {
+	oc := &OrderController{
+		port:              cfg.Port,
+		adminToken:        cfg.AdminToken,
+		service:           service,
+		cacheService:      cacheService,
+		deadLetterService: deadLetterService,
+		monitor:           monitor,
+		ingestion:         ingestion,
 	}
 
-	return &OrderController{
-		port:    port,
-		service: service,
-	}
-}
-
-func (oc *OrderController) StartServer() {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/orders/", oc.getOrderByID)
+	oc.registerAdminRoutes(mux)
+	// The dead-letter topic can only be browsed when ingesting from Kafka.
+	if oc.deadLetterService != nil {
+		oc.registerDeadLetterRoutes(mux)
+	}
+	oc.registerMonitoringRoutes(mux)
+
+	oc.server = &http.Server{
+		Addr:    fmt.Sprintf(":%v", oc.port),
+		Handler: mux,
+	}
+	return oc
+}
 
+// StartServer blocks until the server fails or is shut down. A server shut
+// down before it started returns at once.
+func (oc *OrderController) StartServer() error {
+	if oc.adminToken == "" {
+		logger.Log.Warn("ADMIN_TOKEN is not set, admin endpoints are disabled")
+	}
 	logger.Log.Info("Starting server", "port", oc.port)
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%v", oc.port), mux); err != nil {
-		panic(err)
+	if err := oc.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
+}
+
+func (oc *OrderController) Shutdown(ctx context.Context) error {
+	return oc.server.Shutdown(ctx)
 }
 
 func (oc *OrderController) getOrderByID(w http.ResponseWriter, r *http.Request) {
@@ -54,7 +86,7 @@ func (oc *OrderController) getOrderByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	order, err := oc.service.GetOrderByID(id)
+	order, err := oc.service.GetOrderByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
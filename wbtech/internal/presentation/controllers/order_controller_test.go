@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agl/wbtech/pkg/config"
+)
+
+func TestShutdownBeforeStart(t *testing.T) {
+	oc := NewOrderController(config.HTTP{}, nil, nil, nil, nil, nil)
+	if err := oc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- oc.StartServer() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("StartServer after Shutdown = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server started after Shutdown")
+	}
+}
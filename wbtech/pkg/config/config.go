@@ -0,0 +1,344 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings shared by the service binaries. Every field can be
+// set from the config file (yaml/toml key), the environment (env) and the
+// command line (flag); see Load for the precedence.
+type Config struct {
+	Postgres   Postgres   `yaml:"postgres" toml:"postgres"`
+	HTTP       HTTP       `yaml:"http" toml:"http"`
+	Log        Log        `yaml:"log" toml:"log"`
+	Migrations Migrations `yaml:"migrations" toml:"migrations"`
+	Kafka      Kafka      `yaml:"kafka" toml:"kafka"`
+	NATS       NATS       `yaml:"nats" toml:"nats"`
+	Ingest     Ingest     `yaml:"ingest" toml:"ingest"`
+	Cache      Cache      `yaml:"cache" toml:"cache"`
+	Orders     Orders     `yaml:"orders" toml:"orders"`
+	Monitoring Monitoring `yaml:"monitoring" toml:"monitoring"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" default:"30s" usage:"time allowed for in-flight work on shutdown"`
+}
+
+type Postgres struct {
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST" flag:"db-host" required:"true" usage:"Postgres host"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT" flag:"db-port" default:"5432" usage:"Postgres port"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER" flag:"db-user" required:"true" usage:"Postgres user"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" flag:"db-password" secret:"true" usage:"Postgres password"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME" flag:"db-name" required:"true" usage:"Postgres database"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode" default:"disable" usage:"Postgres sslmode"`
+
+	ConnectAttempts int           `yaml:"connect_attempts" toml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS" flag:"db-connect-attempts" default:"10" usage:"Postgres ping attempts at startup"`
+	ConnectInterval time.Duration `yaml:"connect_interval" toml:"connect_interval" env:"DB_CONNECT_INTERVAL" flag:"db-connect-interval" default:"2s" usage:"delay between Postgres ping attempts"`
+	HealthInterval  time.Duration `yaml:"health_interval" toml:"health_interval" env:"DB_HEALTH_INTERVAL" flag:"db-health-interval" default:"5s" usage:"how often ingestion checks that Postgres is up"`
+}
+
+// DSN returns the connection string for the pgx driver.
+func (p Postgres) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		p.Host, p.Port, p.User, p.Password, p.Name, p.SSLMode)
+}
+
+type HTTP struct {
+	Port       int    `yaml:"port" toml:"port" env:"PORT" flag:"port" default:"8080" usage:"HTTP listen port"`
+	AdminToken string `yaml:"admin_token" toml:"admin_token" env:"ADMIN_TOKEN" flag:"admin-token" secret:"true" usage:"bearer token for /admin endpoints; they answer 403 while it is empty"`
+}
+
+type Log struct {
+	Level string `yaml:"level" toml:"level" env:"LOG_LEVEL" flag:"log-level" default:"info" usage:"log level: debug, info, warn or error"`
+}
+
+type Migrations struct {
+	Path string `yaml:"path" toml:"path" env:"MIGRATIONS_PATH" flag:"migrations-path" usage:"migrations source URL, e.g. file://db/migrations"`
+}
+
+// Kafka is checked further by kafka.NewConfig, which knows the client's
+// supported versions and strategies.
+type Kafka struct {
+	Brokers           []string      `yaml:"brokers" toml:"brokers" env:"KAFKA_BROKERS" flag:"kafka-brokers" default:"kafka:9092" usage:"comma-separated Kafka brokers"`
+	Topics            []string      `yaml:"topics" toml:"topics" env:"KAFKA_TOPICS" flag:"kafka-topics" default:"service.message" usage:"comma-separated topics to consume"`
+	GroupID           string        `yaml:"group_id" toml:"group_id" env:"KAFKA_GROUP_ID" flag:"kafka-group-id" default:"order-api-group" usage:"consumer group"`
+	Version           string        `yaml:"version" toml:"version" env:"KAFKA_VERSION" flag:"kafka-version" default:"2.1.0" usage:"Kafka protocol version"`
+	InitialOffset     string        `yaml:"initial_offset" toml:"initial_offset" env:"KAFKA_INITIAL_OFFSET" flag:"kafka-initial-offset" default:"newest" usage:"where a new group starts: oldest or newest"`
+	SessionTimeout    time.Duration `yaml:"session_timeout" toml:"session_timeout" env:"KAFKA_SESSION_TIMEOUT" flag:"kafka-session-timeout" default:"10s" usage:"consumer group session timeout"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" toml:"heartbeat_interval" env:"KAFKA_HEARTBEAT_INTERVAL" flag:"kafka-heartbeat-interval" default:"3s" usage:"consumer group heartbeat interval"`
+	FetchMinBytes     int32         `yaml:"fetch_min_bytes" toml:"fetch_min_bytes" env:"KAFKA_FETCH_MIN_BYTES" flag:"kafka-fetch-min-bytes" default:"1" usage:"minimum bytes per fetch"`
+	FetchDefaultBytes int32         `yaml:"fetch_default_bytes" toml:"fetch_default_bytes" env:"KAFKA_FETCH_DEFAULT_BYTES" flag:"kafka-fetch-default-bytes" default:"1048576" usage:"default bytes per fetch"`
+	FetchMaxBytes     int32         `yaml:"fetch_max_bytes" toml:"fetch_max_bytes" env:"KAFKA_FETCH_MAX_BYTES" flag:"kafka-fetch-max-bytes" usage:"maximum bytes per fetch, 0 for unlimited"`
+	RebalanceStrategy string        `yaml:"rebalance_strategy" toml:"rebalance_strategy" env:"KAFKA_REBALANCE_STRATEGY" flag:"kafka-rebalance-strategy" default:"range" usage:"range, roundrobin or sticky"`
+
+	TLS  KafkaTLS  `yaml:"tls" toml:"tls"`
+	SASL KafkaSASL `yaml:"sasl" toml:"sasl"`
+}
+
+type KafkaTLS struct {
+	Enabled            bool   `yaml:"enabled" toml:"enabled" env:"KAFKA_TLS_ENABLED" flag:"kafka-tls" usage:"connect to Kafka over TLS"`
+	CAFile             string `yaml:"ca_file" toml:"ca_file" env:"KAFKA_TLS_CA_FILE" flag:"kafka-tls-ca-file" usage:"PEM file with the CA certificates"`
+	CertFile           string `yaml:"cert_file" toml:"cert_file" env:"KAFKA_TLS_CERT_FILE" flag:"kafka-tls-cert-file" usage:"PEM client certificate"`
+	KeyFile            string `yaml:"key_file" toml:"key_file" env:"KAFKA_TLS_KEY_FILE" flag:"kafka-tls-key-file" usage:"PEM client key"`
+	ServerName         string `yaml:"server_name" toml:"server_name" env:"KAFKA_TLS_SERVER_NAME" flag:"kafka-tls-server-name" usage:"expected broker certificate name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" toml:"insecure_skip_verify" env:"KAFKA_TLS_INSECURE_SKIP_VERIFY" flag:"kafka-tls-insecure-skip-verify" usage:"do not verify broker certificates"`
+}
+
+type KafkaSASL struct {
+	Mechanism string `yaml:"mechanism" toml:"mechanism" env:"KAFKA_SASL_MECHANISM" flag:"kafka-sasl-mechanism" usage:"PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, empty to disable"`
+	Username  string `yaml:"username" toml:"username" env:"KAFKA_SASL_USERNAME" flag:"kafka-sasl-username" usage:"SASL user"`
+	Password  string `yaml:"password" toml:"password" env:"KAFKA_SASL_PASSWORD" flag:"kafka-sasl-password" secret:"true" usage:"SASL password"`
+}
+
+type NATS struct {
+	URL       string `yaml:"url" toml:"url" env:"NATS_URL" flag:"nats-url" default:"nats://127.0.0.1:4222" usage:"NATS server URL"`
+	Name      string `yaml:"name" toml:"name" env:"NATS_NAME" flag:"nats-name" default:"order-api" usage:"NATS connection name"`
+	CredsFile string `yaml:"creds_file" toml:"creds_file" env:"NATS_CREDS_FILE" flag:"nats-creds-file" usage:"NATS credentials file"`
+	Stream    string `yaml:"stream" toml:"stream" env:"NATS_STREAM" flag:"nats-stream" default:"ORDERS" usage:"JetStream stream, created if missing"`
+	// Subjects are consumed by the service; a missing stream is created with
+	// them.
+	Subjects      []string      `yaml:"subjects" toml:"subjects" env:"NATS_SUBJECTS" flag:"nats-subjects" default:"service.message" usage:"comma-separated subjects to consume"`
+	Durable       string        `yaml:"durable" toml:"durable" env:"NATS_DURABLE" flag:"nats-durable" default:"order-api-group" usage:"durable consumer name"`
+	AckWait       time.Duration `yaml:"ack_wait" toml:"ack_wait" env:"NATS_ACK_WAIT" flag:"nats-ack-wait" default:"30s" usage:"time before an unacknowledged message is redelivered"`
+	MaxAckPending int           `yaml:"max_ack_pending" toml:"max_ack_pending" env:"NATS_MAX_ACK_PENDING" flag:"nats-max-ack-pending" default:"256" usage:"maximum unacknowledged messages"`
+	FetchBatch    int           `yaml:"fetch_batch" toml:"fetch_batch" env:"NATS_FETCH_BATCH" flag:"nats-fetch-batch" default:"16" usage:"messages per fetch"`
+	FetchMaxWait  time.Duration `yaml:"fetch_max_wait" toml:"fetch_max_wait" env:"NATS_FETCH_MAX_WAIT" flag:"nats-fetch-max-wait" default:"5s" usage:"how long a fetch waits for messages"`
+	// NakDelay is how long JetStream waits before redelivering a message that
+	// was not acknowledged.
+	NakDelay time.Duration `yaml:"nak_delay" toml:"nak_delay" env:"NATS_NAK_DELAY" flag:"nats-nak-delay" default:"1s" usage:"redelivery delay after a failed message"`
+}
+
+type Ingest struct {
+	// Broker selects where orders are consumed from. The in-memory broker
+	// needs no external service and is meant for local runs and tests.
+	Broker          string `yaml:"broker" toml:"broker" env:"INGEST_BROKER" flag:"ingest-broker" default:"kafka" usage:"kafka, nats or memory"`
+	DeadLetterTopic string `yaml:"dead_letter_topic" toml:"dead_letter_topic" env:"DLQ_TOPIC" flag:"dlq-topic" default:"service.message.dlq" usage:"topic for messages that cannot be stored"`
+	MemorySeed      string `yaml:"memory_seed" toml:"memory_seed" env:"MEMORY_BROKER_SEED" flag:"memory-broker-seed" usage:"path to a file containing a JSON array of orders, published on startup to the first kafka topic when the broker is memory"`
+
+	Workers   int    `yaml:"workers" toml:"workers" env:"INGEST_WORKERS" flag:"ingest-workers" default:"4" usage:"message handler workers"`
+	QueueSize int    `yaml:"queue_size" toml:"queue_size" env:"INGEST_QUEUE_SIZE" flag:"ingest-queue-size" default:"16" usage:"messages queued per worker"`
+	Ordering  string `yaml:"ordering" toml:"ordering" env:"INGEST_ORDERING" flag:"ingest-ordering" default:"order_uid" usage:"key messages are serialized by: order_uid or partition"`
+	// BatchSize above one makes every worker collect up to that many messages,
+	// or whatever arrived within BatchInterval of the first, before handling
+	// them together.
+	BatchSize     int           `yaml:"batch_size" toml:"batch_size" env:"INGEST_BATCH_SIZE" flag:"ingest-batch-size" default:"1" usage:"orders stored per transaction"`
+	BatchInterval time.Duration `yaml:"batch_interval" toml:"batch_interval" env:"INGEST_BATCH_INTERVAL" flag:"ingest-batch-interval" default:"100ms" usage:"how long a batch waits to fill up"`
+	DrainTimeout  time.Duration `yaml:"drain_timeout" toml:"drain_timeout" env:"CONSUMER_DRAIN_TIMEOUT" flag:"consumer-drain-timeout" default:"20s" usage:"time in-flight messages get on shutdown or rebalance"`
+
+	Retry Retry `yaml:"retry" toml:"retry"`
+}
+
+// Retry converts to retry.Policy.
+type Retry struct {
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts" env:"INGEST_RETRY_MAX_ATTEMPTS" flag:"ingest-retry-max-attempts" default:"5" usage:"attempts to store a message"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" toml:"initial_backoff" env:"INGEST_RETRY_INITIAL_BACKOFF" flag:"ingest-retry-initial-backoff" default:"200ms" usage:"delay before the first retry"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" toml:"max_backoff" env:"INGEST_RETRY_MAX_BACKOFF" flag:"ingest-retry-max-backoff" default:"30s" usage:"longest delay between retries"`
+}
+
+type Cache struct {
+	Backend     string        `yaml:"backend" toml:"backend" env:"CACHE_BACKEND" flag:"cache-backend" default:"memory" usage:"memory, redis or tiered"`
+	MaxEntries  int           `yaml:"max_entries" toml:"max_entries" env:"CACHE_MAX_ENTRIES" flag:"cache-max-entries" default:"100000" usage:"orders kept in memory, 0 for no limit"`
+	MaxBytes    int64         `yaml:"max_bytes" toml:"max_bytes" env:"CACHE_MAX_BYTES" flag:"cache-max-bytes" default:"268435456" usage:"approximate memory for cached orders, 0 for no limit"`
+	Policy      string        `yaml:"policy" toml:"policy" env:"CACHE_POLICY" flag:"cache-policy" default:"lru" usage:"eviction policy: lru or lfu"`
+	TTL         time.Duration `yaml:"ttl" toml:"ttl" env:"CACHE_TTL" flag:"cache-ttl" usage:"lifetime of cached orders, 0 for none"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" toml:"negative_ttl" env:"CACHE_NEGATIVE_TTL" flag:"cache-negative-ttl" default:"5s" usage:"how long unknown order uids are remembered"`
+	L1TTL       time.Duration `yaml:"l1_ttl" toml:"l1_ttl" env:"CACHE_L1_TTL" flag:"cache-l1-ttl" default:"30s" usage:"lifetime of orders in the local tier of the tiered cache"`
+
+	Redis        Redis        `yaml:"redis" toml:"redis"`
+	Snapshot     Snapshot     `yaml:"snapshot" toml:"snapshot"`
+	WarmUp       WarmUp       `yaml:"warmup" toml:"warmup"`
+	Invalidation Invalidation `yaml:"invalidation" toml:"invalidation"`
+}
+
+type Redis struct {
+	Addr     string `yaml:"addr" toml:"addr" env:"REDIS_ADDR" flag:"redis-addr" usage:"Redis address, required by the redis and tiered backends"`
+	Password string `yaml:"password" toml:"password" env:"REDIS_PASSWORD" flag:"redis-password" secret:"true" usage:"Redis password"`
+	DB       int    `yaml:"db" toml:"db" env:"REDIS_DB" flag:"redis-db" usage:"Redis database"`
+	Prefix   string `yaml:"prefix" toml:"prefix" env:"CACHE_REDIS_PREFIX" flag:"cache-redis-prefix" default:"order:" usage:"prefix of the order keys in Redis"`
+}
+
+type Snapshot struct {
+	Path     string        `yaml:"path" toml:"path" env:"CACHE_SNAPSHOT_PATH" flag:"cache-snapshot-path" usage:"file the cache is saved to and restored from; empty disables snapshots"`
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"CACHE_SNAPSHOT_INTERVAL" flag:"cache-snapshot-interval" default:"5m" usage:"how often the snapshot is saved"`
+}
+
+type WarmUp struct {
+	Limit     int `yaml:"limit" toml:"limit" env:"CACHE_WARMUP_LIMIT" flag:"cache-warmup-limit" usage:"orders loaded on startup, 0 for all"`
+	Days      int `yaml:"days" toml:"days" env:"CACHE_WARMUP_DAYS" flag:"cache-warmup-days" usage:"only load orders created in the last days, 0 for all"`
+	ChunkSize int `yaml:"chunk_size" toml:"chunk_size" env:"CACHE_WARMUP_CHUNK_SIZE" flag:"cache-warmup-chunk-size" default:"1000" usage:"orders per warm-up query"`
+	Workers   int `yaml:"workers" toml:"workers" env:"CACHE_WARMUP_WORKERS" flag:"cache-warmup-workers" default:"4" usage:"parallel warm-up loaders"`
+}
+
+type Invalidation struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled" env:"CACHE_INVALIDATION_ENABLED" flag:"cache-invalidation" default:"true" usage:"tell other replicas about changed orders; requires kafka"`
+	Topic   string `yaml:"topic" toml:"topic" env:"CACHE_INVALIDATION_TOPIC" flag:"cache-invalidation-topic" default:"service.cache-invalidation" usage:"topic for cache invalidations"`
+	// ReplicaID prefixes the origin of the invalidations this process
+	// publishes; the hostname is used when it is empty.
+	ReplicaID string `yaml:"replica_id" toml:"replica_id" env:"REPLICA_ID" flag:"replica-id" usage:"name of this replica"`
+}
+
+type Orders struct {
+	ConflictPolicy string        `yaml:"conflict_policy" toml:"conflict_policy" env:"ORDER_CONFLICT_POLICY" flag:"order-conflict-policy" default:"keep-newest" usage:"what a changed order does to a stored one: reject, overwrite or keep-newest"`
+	ReadTimeout    time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"DB_READ_TIMEOUT" flag:"db-read-timeout" default:"5s" usage:"timeout of order queries"`
+	WriteTimeout   time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"DB_WRITE_TIMEOUT" flag:"db-write-timeout" default:"10s" usage:"timeout of order writes"`
+
+	Invariants Invariants `yaml:"invariants" toml:"invariants"`
+}
+
+type Invariants struct {
+	Mode  string   `yaml:"mode" toml:"mode" env:"ORDER_INVARIANT_MODE" flag:"order-invariant-mode" default:"lenient" usage:"strict rejects orders breaking an invariant, lenient stores them with warnings, off skips the checks"`
+	Rules []string `yaml:"rules" toml:"rules" env:"ORDER_INVARIANT_RULES" flag:"order-invariant-rules" default:"goods_total,payment_amount,item_total_price,item_track_number" usage:"comma-separated invariants to check"`
+	// PriceTolerance is how far an item's total price may be off the price
+	// with the sale applied, to allow for rounding.
+	PriceTolerance int `yaml:"price_tolerance" toml:"price_tolerance" env:"ORDER_INVARIANT_PRICE_TOLERANCE" flag:"order-invariant-price-tolerance" default:"1" usage:"allowed rounding difference of item total prices"`
+}
+
+type Monitoring struct {
+	// LagThreshold is the total lag above which the service reports itself as
+	// not ready; zero disables the check.
+	LagThreshold int64         `yaml:"lag_threshold" toml:"lag_threshold" env:"CONSUMER_LAG_THRESHOLD" flag:"consumer-lag-threshold" usage:"consumer lag that makes /ready fail, 0 to disable"`
+	LagInterval  time.Duration `yaml:"lag_interval" toml:"lag_interval" env:"CONSUMER_LAG_INTERVAL" flag:"consumer-lag-interval" default:"15s" usage:"how often consumer lag is polled"`
+}
+
+// Validate reports every missing or invalid value at once.
+func (c *Config) Validate() error {
+	return c.validate(nil)
+}
+
+func (c *Config) validate(skip map[string]bool) error {
+	var errs []error
+
+	walk(c, func(f field) {
+		if skip[f.section] {
+			return
+		}
+		if f.tag.Get("required") == "true" && f.value.IsZero() {
+			errs = append(errs, fmt.Errorf("%s is required (%s)", f.path, f.tag.Get("env")))
+		}
+	})
+
+	if c.Postgres.Port <= 0 || c.Postgres.Port > 65535 {
+		errs = append(errs, fmt.Errorf("postgres.port %d is out of range", c.Postgres.Port))
+	}
+	if c.Postgres.ConnectAttempts <= 0 {
+		errs = append(errs, errors.New("postgres.connect_attempts must be positive"))
+	}
+	if c.HTTP.Port <= 0 || c.HTTP.Port > 65535 {
+		errs = append(errs, fmt.Errorf("http.port %d is out of range", c.HTTP.Port))
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q is not one of debug, info, warn, error", c.Log.Level))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	if c.Postgres.HealthInterval <= 0 {
+		errs = append(errs, errors.New("postgres.health_interval must be positive"))
+	}
+
+	errs = append(errs, oneOf("ingest.broker", c.Ingest.Broker, "kafka", "nats", "memory"))
+	errs = append(errs, oneOf("ingest.ordering", c.Ingest.Ordering, "order_uid", "partition"))
+	for path, n := range map[string]int{
+		"ingest.workers":            c.Ingest.Workers,
+		"ingest.queue_size":         c.Ingest.QueueSize,
+		"ingest.batch_size":         c.Ingest.BatchSize,
+		"ingest.retry.max_attempts": c.Ingest.Retry.MaxAttempts,
+		"cache.warmup.chunk_size":   c.Cache.WarmUp.ChunkSize,
+		"cache.warmup.workers":      c.Cache.WarmUp.Workers,
+	} {
+		if n <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", path))
+		}
+	}
+	for path, d := range map[string]time.Duration{
+		"ingest.batch_interval":        c.Ingest.BatchInterval,
+		"ingest.retry.initial_backoff": c.Ingest.Retry.InitialBackoff,
+		"ingest.retry.max_backoff":     c.Ingest.Retry.MaxBackoff,
+		"cache.snapshot.interval":      c.Cache.Snapshot.Interval,
+		"orders.read_timeout":          c.Orders.ReadTimeout,
+		"orders.write_timeout":         c.Orders.WriteTimeout,
+		"monitoring.lag_interval":      c.Monitoring.LagInterval,
+	} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", path))
+		}
+	}
+
+	errs = append(errs, oneOf("cache.backend", c.Cache.Backend, "memory", "redis", "tiered"))
+	errs = append(errs, oneOf("cache.policy", c.Cache.Policy, "lru", "lfu"))
+	if c.Cache.Backend != "memory" && c.Cache.Redis.Addr == "" {
+		errs = append(errs, fmt.Errorf("cache.redis.addr is required for cache backend %q (REDIS_ADDR)", c.Cache.Backend))
+	}
+	if c.Cache.MaxEntries < 0 || c.Cache.MaxBytes < 0 || c.Cache.TTL < 0 || c.Cache.NegativeTTL < 0 || c.Cache.L1TTL < 0 {
+		errs = append(errs, errors.New("cache limits and TTLs must not be negative"))
+	}
+	if c.Cache.WarmUp.Limit < 0 || c.Cache.WarmUp.Days < 0 {
+		errs = append(errs, errors.New("cache.warmup.limit and cache.warmup.days must not be negative"))
+	}
+
+	errs = append(errs, oneOf("orders.conflict_policy", c.Orders.ConflictPolicy, "reject", "overwrite", "keep-newest"))
+	errs = append(errs, oneOf("orders.invariants.mode", c.Orders.Invariants.Mode, "strict", "lenient", "off"))
+	for _, rule := range c.Orders.Invariants.Rules {
+		errs = append(errs, oneOf("orders.invariants.rules", rule, "goods_total", "payment_amount", "item_total_price", "item_track_number"))
+	}
+	if c.Orders.Invariants.PriceTolerance < 0 {
+		errs = append(errs, errors.New("orders.invariants.price_tolerance must not be negative"))
+	}
+	if c.Monitoring.LagThreshold < 0 {
+		errs = append(errs, errors.New("monitoring.lag_threshold must not be negative"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+func oneOf(path, value string, allowed ...string) error {
+	if slices.Contains(allowed, value) {
+		return nil
+	}
+	return fmt.Errorf("%s %q is not one of %s", path, value, strings.Join(allowed, ", "))
+}
+
+// String renders the configuration one key per line with secrets redacted, so
+// it is safe to print or log.
+func (c *Config) String() string {
+	var b strings.Builder
+	walk(c, func(f field) {
+		fmt.Fprintf(&b, "%s=%s\n", f.path, f.display())
+	})
+	return b.String()
+}
+
+func (f field) display() string {
+	if f.tag.Get("secret") == "true" {
+		if f.value.IsZero() {
+			return ""
+		}
+		return "[REDACTED]"
+	}
+	switch v := f.value.Interface().(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		return strconv.Quote(strings.Join(v, ","))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// LogValue logs the configuration as flat, redacted attributes.
+func (c *Config) LogValue() slog.Value {
+	var attrs []slog.Attr
+	walk(c, func(f field) {
+		attrs = append(attrs, slog.String(f.path, f.display()))
+	})
+	return slog.GroupValue(attrs...)
+}
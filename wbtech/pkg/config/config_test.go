@@ -0,0 +1,58 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecretsRedacted(t *testing.T) {
+	const secret = "hunter2"
+
+	tests := []struct {
+		name string
+		set  func(c *Config)
+		path string
+		want string
+		// shown is set for values that are not secret.
+		shown bool
+	}{
+		{name: "postgres password", set: func(c *Config) { c.Postgres.Password = secret }, path: "postgres.password", want: "[REDACTED]"},
+		{name: "admin token", set: func(c *Config) { c.HTTP.AdminToken = secret }, path: "http.admin_token", want: "[REDACTED]"},
+		{name: "sasl password", set: func(c *Config) { c.Kafka.SASL.Password = secret }, path: "kafka.sasl.password", want: "[REDACTED]"},
+		{name: "redis password", set: func(c *Config) { c.Cache.Redis.Password = secret }, path: "cache.redis.password", want: "[REDACTED]"},
+		{name: "empty secret is shown empty", set: func(*Config) {}, path: "postgres.password", want: ""},
+		{name: "plain value is shown", set: func(c *Config) { c.Postgres.User = secret }, path: "postgres.user", want: `"hunter2"`, shown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			tt.set(cfg)
+			s := cfg.String()
+			if line := tt.path + "=" + tt.want + "\n"; !strings.Contains(s, line) {
+				t.Errorf("String() has no line %q", line)
+			}
+			if !tt.shown && strings.Contains(s, secret) {
+				t.Error("String() leaks the secret")
+			}
+
+			var got string
+			for _, attr := range cfg.LogValue().Group() {
+				if attr.Key == tt.path {
+					got = attr.Value.String()
+				}
+			}
+			if got != tt.want {
+				t.Errorf("LogValue()[%s] = %q, want %q", tt.path, got, tt.want)
+			}
+
+			var buf bytes.Buffer
+			slog.New(slog.NewTextHandler(&buf, nil)).Info("config", "config", cfg)
+			if !tt.shown && strings.Contains(buf.String(), secret) {
+				t.Error("logged config leaks the secret")
+			}
+		})
+	}
+}
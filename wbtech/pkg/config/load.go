@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Option changes how Load validates the configuration.
+type Option func(*options)
+
+type options struct {
+	skip map[string]bool
+}
+
+// Without leaves the named sections, by their yaml key, out of the required
+// value checks, for tools that never connect to them.
+func Without(sections ...string) Option {
+	return func(o *options) {
+		for _, s := range sections {
+			o.skip[s] = true
+		}
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence: the
+// defaults, the file named by -config or CONFIG_FILE (.yaml, .yml or .toml),
+// the environment, and the command-line flags in args. The result is
+// validated.
+func Load(name string, args []string, opts ...Option) (*Config, error) {
+	cfg := &Config{}
+	o := options{skip: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var err error
+	walk(cfg, func(f field) {
+		if def := f.tag.Get("default"); def != "" && err == nil {
+			err = f.set(def)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flags := make(map[string]*string)
+	walk(cfg, func(f field) {
+		if name := f.tag.Get("flag"); name != "" {
+			flags[name] = fs.String(name, "", f.tag.Get("usage"))
+		}
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, fmt.Errorf("read config file %s: %w", *configFile, err)
+		}
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	walk(cfg, func(f field) {
+		if err != nil {
+			return
+		}
+		if v, ok := os.LookupEnv(f.tag.Get("env")); ok && v != "" {
+			if err = f.set(v); err != nil {
+				err = fmt.Errorf("%s: %w", f.tag.Get("env"), err)
+				return
+			}
+		}
+		if name := f.tag.Get("flag"); set[name] {
+			if err = f.set(*flags[name]); err != nil {
+				err = fmt.Errorf("-%s: %w", name, err)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(o.skip); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		return dec.Decode(cfg)
+	case ".toml":
+		md, err := toml.NewDecoder(bytes.NewReader(b)).Decode(cfg)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys %v", undecoded)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported config file extension %q", ext)
+	}
+}
+
+type field struct {
+	path    string
+	section string
+	tag     reflect.StructTag
+	value   reflect.Value
+}
+
+// walk calls fn for every leaf field of cfg, naming it by its yaml keys.
+func walk(cfg *Config, fn func(f field)) {
+	walkStruct(reflect.ValueOf(cfg).Elem(), "", fn)
+}
+
+func walkStruct(v reflect.Value, prefix string, fn func(f field)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		path := prefix + sf.Tag.Get("yaml")
+		if sf.Type.Kind() == reflect.Struct {
+			walkStruct(v.Field(i), path+".", fn)
+			continue
+		}
+		section, _, _ := strings.Cut(path, ".")
+		fn(field{path: path, section: section, tag: sf.Tag, value: v.Field(i)})
+	}
+}
+
+// set parses s into the field. Lists are comma-separated.
+func (f field) set(s string) error {
+	v := f.value
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(x)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported config field type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config field type %s", v.Type())
+	}
+	return nil
+}
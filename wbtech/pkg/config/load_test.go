@@ -0,0 +1,247 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// setRequired sets the required postgres values in the environment, so tests
+// only fail on what they are about.
+func setRequired(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_HOST", "db")
+	t.Setenv("DB_USER", "orders")
+	t.Setenv("DB_NAME", "orders")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := "http:\n  port: 9001\nkafka:\n  topics: [a, b]\n"
+	tomlFile := "[http]\nport = 9001\n[kafka]\ntopics = [\"a\", \"b\"]\n"
+
+	tests := []struct {
+		name       string
+		file       string
+		fileName   string
+		env        map[string]string
+		args       []string
+		wantPort   int
+		wantTopics []string
+	}{
+		{
+			name:       "defaults",
+			wantPort:   8080,
+			wantTopics: []string{"service.message"},
+		},
+		{
+			name:       "yaml file overrides the defaults",
+			file:       yamlFile,
+			fileName:   "config.yaml",
+			wantPort:   9001,
+			wantTopics: []string{"a", "b"},
+		},
+		{
+			name:       "toml file overrides the defaults",
+			file:       tomlFile,
+			fileName:   "config.toml",
+			wantPort:   9001,
+			wantTopics: []string{"a", "b"},
+		},
+		{
+			name:       "environment overrides the file",
+			file:       yamlFile,
+			fileName:   "config.yml",
+			env:        map[string]string{"PORT": "9002", "KAFKA_TOPICS": "c, d,"},
+			wantPort:   9002,
+			wantTopics: []string{"c", "d"},
+		},
+		{
+			name:       "empty environment value is ignored",
+			file:       yamlFile,
+			fileName:   "config.yaml",
+			env:        map[string]string{"PORT": ""},
+			wantPort:   9001,
+			wantTopics: []string{"a", "b"},
+		},
+		{
+			name:       "flag overrides the environment",
+			file:       yamlFile,
+			fileName:   "config.yaml",
+			env:        map[string]string{"PORT": "9002"},
+			args:       []string{"-port", "9003"},
+			wantPort:   9003,
+			wantTopics: []string{"a", "b"},
+		},
+		{
+			name:       "flag overrides the defaults",
+			args:       []string{"-port=9003", "-kafka-topics=e"},
+			wantPort:   9003,
+			wantTopics: []string{"e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv("PORT", "")
+			t.Setenv("KAFKA_TOPICS", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.fileName, tt.file)}, args...)
+			}
+
+			cfg, err := Load("test", args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.HTTP.Port != tt.wantPort {
+				t.Errorf("http.port = %d, want %d", cfg.HTTP.Port, tt.wantPort)
+			}
+			if !slices.Equal(cfg.Kafka.Topics, tt.wantTopics) {
+				t.Errorf("kafka.topics = %q, want %q", cfg.Kafka.Topics, tt.wantTopics)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	setRequired(t)
+	t.Setenv("PORT", "")
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "http:\n  port: 9001\n"))
+
+	cfg, err := Load("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTP.Port != 9001 {
+		t.Errorf("http.port = %d, want 9001", cfg.HTTP.Port)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		file     string
+		env      map[string]string
+		args     []string
+		opts     []Option
+		want     []string
+	}{
+		{
+			name: "missing required value names the key",
+			env:  map[string]string{"DB_HOST": ""},
+			want: []string{"postgres.host is required (DB_HOST)"},
+		},
+		{
+			name: "every missing required value is reported",
+			env:  map[string]string{"DB_HOST": "", "DB_NAME": ""},
+			want: []string{"postgres.host is required (DB_HOST)", "postgres.name is required (DB_NAME)"},
+		},
+		{
+			name:     "unknown yaml key",
+			fileName: "config.yaml",
+			file:     "http:\n  prot: 9001\n",
+			want:     []string{"prot"},
+		},
+		{
+			name:     "unknown toml key",
+			fileName: "config.toml",
+			file:     "[http]\nprot = 9001\n",
+			want:     []string{"unknown keys", "http.prot"},
+		},
+		{
+			name:     "unsupported file extension",
+			fileName: "config.json",
+			file:     "{}",
+			want:     []string{`unsupported config file extension ".json"`},
+		},
+		{
+			name: "invalid environment value names the variable",
+			env:  map[string]string{"PORT": "eighty"},
+			want: []string{"PORT:"},
+		},
+		{
+			name: "invalid flag value names the flag",
+			args: []string{"-ingest-batch-interval", "soon"},
+			want: []string{"-ingest-batch-interval:"},
+		},
+		{
+			name: "values are validated",
+			args: []string{"-cache-backend", "redis", "-ingest-workers", "0"},
+			want: []string{"cache.redis.addr is required", "ingest.workers must be positive"},
+		},
+		{
+			name: "skipped section still gets range checks",
+			env:  map[string]string{"DB_HOST": ""},
+			args: []string{"-db-port", "0"},
+			opts: []Option{Without("postgres")},
+			want: []string{"postgres.port 0 is out of range"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv("PORT", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.fileName, tt.file)}, args...)
+			}
+
+			_, err := Load("test", args, tt.opts...)
+			if err == nil {
+				t.Fatal("Load succeeded")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWithout(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "postgres required by default", wantErr: true},
+		{name: "postgres skipped", opts: []Option{Without("postgres")}},
+		{name: "other section skipped", opts: []Option{Without("kafka")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv("PORT", "")
+			t.Setenv("DB_HOST", "")
+			t.Setenv("DB_USER", "")
+
+			_, err := Load("test", nil, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,41 +3,25 @@ package dbconnections
 import (
 	"database/sql"
 	"log"
-	"os"
 	"time"
 
+	"github.com/agl/wbtech/pkg/config"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-func InitPostgres() *sql.DB {
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	if port == "" {
-		port = "5432"
-	}
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-
-	dsn := "host=" + host +
-		" port=" + port +
-		" user=" + user +
-		" password=" + password +
-		" dbname=" + dbname +
-		" sslmode=disable"
-
-	db, err := sql.Open("pgx", dsn)
+func InitPostgres(cfg config.Postgres) *sql.DB {
+	db, err := sql.Open("pgx", cfg.DSN())
 	if err != nil {
 		log.Fatalf("failed to connect to DB: %v", err)
 	}
 
-	for i := range 10 {
+	for i := range cfg.ConnectAttempts {
 		err = db.Ping()
 		if err == nil {
 			break
 		}
 		log.Printf("retrying DB connection (%d)...\n", i+1)
-		time.Sleep(2 * time.Second)
+		time.Sleep(cfg.ConnectInterval)
 	}
 
 	if err != nil {
@@ -0,0 +1,149 @@
+// Package kafkasecurity sets up TLS and SASL on sarama clients from the
+// kafka.tls and kafka.sasl settings. The order service and the emulator both
+// use it.
+package kafkasecurity
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/agl/wbtech/pkg/config"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// Security holds validated settings with the certificates already loaded, so
+// applying it to a client cannot fail.
+type Security struct {
+	tls  *tls.Config
+	sasl config.KafkaSASL
+}
+
+// New checks the settings and loads the certificates, reporting every problem
+// at once.
+func New(tlsCfg config.KafkaTLS, sasl config.KafkaSASL) (*Security, error) {
+	var errs []error
+	s := &Security{sasl: sasl}
+	s.sasl.Mechanism = strings.ToUpper(sasl.Mechanism)
+
+	if tlsCfg.Enabled {
+		tlsConfig, err := buildTLS(tlsCfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tls: %w", err))
+		}
+		s.tls = tlsConfig
+	}
+
+	switch s.sasl.Mechanism {
+	case "":
+	case SASLPlain, SASLScramSHA256, SASLScramSHA512:
+		if sasl.Username == "" || sasl.Password == "" {
+			errs = append(errs, errors.New("sasl: username and password are required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("sasl mechanism %q: must be %s, %s or %s", sasl.Mechanism, SASLPlain, SASLScramSHA256, SASLScramSHA512))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func buildTLS(t config.KafkaTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Apply enables TLS and SASL on config. A nil Security leaves it unchanged.
+func (s *Security) Apply(config *sarama.Config) {
+	if s == nil {
+		return
+	}
+	if s.tls != nil {
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = s.tls
+	}
+
+	if s.sasl.Mechanism == "" {
+		return
+	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.User = s.sasl.Username
+	config.Net.SASL.Password = s.sasl.Password
+	config.Net.SASL.Handshake = true
+
+	switch s.sasl.Mechanism {
+	case SASLScramSHA256:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hash: scram.HashGeneratorFcn(sha256.New)}
+		}
+	case SASLScramSHA512:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hash: scram.HashGeneratorFcn(sha512.New)}
+		}
+	default:
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	}
+}
+
+// scramClient adapts xdg-go/scram to sarama's SCRAMClient interface.
+type scramClient struct {
+	hash         scram.HashGeneratorFcn
+	conversation *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hash.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
@@ -9,10 +9,13 @@ import (
 var Log *slog.Logger
 
 func init() {
-	level := parseLogLevel(os.Getenv("LOG_LEVEL"))
+	Configure("info")
+}
 
+// Configure replaces Log with a logger writing at the given level.
+func Configure(level string) {
 	Log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
+		Level: parseLogLevel(level),
 	}))
 }
 
@@ -0,0 +1,74 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil || IsPermanent(err) {
+		return err
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Policy is usually converted from config.Retry.
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns the delay before the given retry (1-based), doubling from
+// InitialBackoff up to MaxBackoff with full jitter.
+func (p Policy) Backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Do calls fn until it succeeds, returns a permanent error or the attempts are
+// exhausted. The last error is returned. Cancelling ctx stops waiting between
+// attempts and returns the context error.
+func Do(ctx context.Context, p Policy, fn func(attempt int) error) error {
+	var err error
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		if err = fn(attempt); err == nil || IsPermanent(err) {
+			return err
+		}
+		if attempt < p.MaxAttempts {
+			timer := time.NewTimer(p.Backoff(attempt))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDo(t *testing.T) {
+	policy := Policy{MaxAttempts: 3}
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      error
+		wantPerm     bool
+	}{
+		{
+			name:         "success on the first attempt",
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name:         "retryable errors are retried until success",
+			errs:         []error{errTransient, errTransient, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "retryable errors exhaust the attempts",
+			errs:         []error{errTransient, errTransient, errTransient},
+			wantAttempts: 3,
+			wantErr:      errTransient,
+		},
+		{
+			name:         "permanent error stops at once",
+			errs:         []error{Permanent(errTransient)},
+			wantAttempts: 1,
+			wantErr:      errTransient,
+			wantPerm:     true,
+		},
+		{
+			name:         "wrapped permanent error stops at once",
+			errs:         []error{errTransient, fmt.Errorf("store: %w", Permanent(errTransient))},
+			wantAttempts: 2,
+			wantErr:      errTransient,
+			wantPerm:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := Do(context.Background(), policy, func(attempt int) error {
+				attempts++
+				if attempt != attempts {
+					t.Errorf("attempt = %d, want %d", attempt, attempts)
+				}
+				return tt.errs[attempt-1]
+			})
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if IsPermanent(err) != tt.wantPerm {
+				t.Errorf("permanent = %v, want %v", IsPermanent(err), tt.wantPerm)
+			}
+		})
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+	attempts := 0
+	err := Do(ctx, policy, func(int) error {
+		attempts++
+		cancel()
+		return errTransient
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) is not nil")
+	}
+	err := Permanent(errTransient)
+	if Permanent(err) != err {
+		t.Error("Permanent wraps a permanent error again")
+	}
+	if !errors.Is(err, errTransient) {
+		t.Error("permanent error does not unwrap to its cause")
+	}
+	if IsPermanent(errTransient) {
+		t.Error("plain error reported as permanent")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{retry: 1, max: 100 * time.Millisecond},
+		{retry: 2, max: 200 * time.Millisecond},
+		{retry: 3, max: 400 * time.Millisecond},
+		{retry: 4, max: 800 * time.Millisecond},
+		{retry: 5, max: time.Second},
+		{retry: 50, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			d := policy.Backoff(tt.retry)
+			if d < tt.max/2 || d > tt.max {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", tt.retry, d, tt.max/2, tt.max)
+			}
+		}
+	}
+
+	if d := (Policy{}).Backoff(1); d != 0 {
+		t.Errorf("zero policy backoff = %v, want 0", d)
+	}
+}
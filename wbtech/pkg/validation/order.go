@@ -0,0 +1,67 @@
+package validation
+
+var (
+	requiredText = Value(Required, NotBlank)
+	optionalText = Value(String)
+	id           = Value(Required, Integer, Min(1))
+	amount       = Value(Required, Integer, Int32, Min(0))
+	optionalSum  = Value(Integer, Int32, Min(0))
+)
+
+// OrderSchema describes an order message. Amounts may be zero; identifiers
+// must be positive.
+var OrderSchema = Object{
+	"order_uid":          requiredText,
+	"track_number":       requiredText,
+	"entry":              requiredText,
+	"locale":             Value(Required, Locale),
+	"internal_signature": optionalText,
+	"customer_id":        requiredText,
+	"delivery_service":   requiredText,
+	"shardkey":           requiredText,
+	"sm_id":              Value(Required, Integer, Int32, Min(1)),
+	"date_created":       Value(Required, RFC3339),
+	"oof_shard":          requiredText,
+	"delivery": Object{
+		"name":    requiredText,
+		"phone":   Value(Required, Phone),
+		"zip":     Value(Required, Zip),
+		"city":    requiredText,
+		"address": requiredText,
+		"region":  requiredText,
+		"email":   Value(Required, Email),
+	},
+	"payment": Object{
+		"transaction":   requiredText,
+		"request_id":    optionalText,
+		"currency":      Value(Required, Currency),
+		"provider":      requiredText,
+		"amount":        amount,
+		"payment_dt":    id,
+		"bank":          requiredText,
+		"delivery_cost": optionalSum,
+		"goods_total":   optionalSum,
+		"custom_fee":    optionalSum,
+	},
+	"items": Array{
+		Min: 1,
+		Items: Object{
+			"chrt_id":      id,
+			"track_number": requiredText,
+			"price":        amount,
+			"rid":          requiredText,
+			"name":         requiredText,
+			"sale":         Value(Integer, Range(0, 100)),
+			"size":         requiredText,
+			"total_price":  amount,
+			"nm_id":        id,
+			"brand":        requiredText,
+			"status":       Value(Required, Integer, Int32, Min(0)),
+		},
+	},
+}
+
+// ValidateOrder checks an order message against OrderSchema.
+func ValidateOrder(data []byte) error {
+	return Validate(data, OrderSchema)
+}
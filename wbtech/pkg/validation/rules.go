@@ -0,0 +1,144 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Rule is a named check of a single value. The check returns an empty string
+// when the value passes and a message otherwise.
+type Rule struct {
+	Name  string
+	check func(v any) string
+}
+
+func NewRule(name string, check func(v any) string) Rule {
+	return Rule{Name: name, check: check}
+}
+
+// Required makes a missing or null value a violation. It is handled by Value
+// itself and has no check.
+var Required = Rule{Name: "required"}
+
+var (
+	String = NewRule("string", func(v any) string {
+		if _, ok := v.(string); !ok {
+			return "must be a string"
+		}
+		return ""
+	})
+
+	NotBlank = stringRule("not_blank", "must not be blank", func(s string) bool {
+		return strings.TrimSpace(s) != ""
+	})
+
+	Email = stringRule("email", "must be an email address", func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	})
+
+	// Phone accepts 7 to 15 digits with an optional leading + and the usual
+	// separators.
+	Phone = stringRule("phone", "must be a phone number", func(s string) bool {
+		if !phonePattern.MatchString(s) {
+			return false
+		}
+		digits := 0
+		for _, r := range s {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		return digits >= 7 && digits <= 15
+	})
+
+	Zip = stringRule("zip", "must be a postal code", zipPattern.MatchString)
+
+	Currency = stringRule("currency", "must be an ISO 4217 currency code", func(s string) bool {
+		return currencies[s]
+	})
+
+	// Locale accepts a language subtag with an optional region, e.g. en or
+	// ru-RU.
+	Locale = stringRule("locale", "must be a locale such as en or en-US", localePattern.MatchString)
+
+	RFC3339 = stringRule("rfc3339", "must be an RFC 3339 timestamp", func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	})
+
+	Integer = NewRule("integer", func(v any) string {
+		if _, ok := integer(v); !ok {
+			return "must be an integer"
+		}
+		return ""
+	})
+
+	// Int32 limits a value to what an INTEGER column holds.
+	Int32 = rangeRule("int32", math.MinInt32, math.MaxInt32)
+)
+
+var (
+	phonePattern  = regexp.MustCompile(`^\+?[0-9 ()-]+$`)
+	zipPattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{1,9}$`)
+	localePattern = regexp.MustCompile(`^[a-z]{2,3}([-_]([A-Z]{2}|[0-9]{3}))?$`)
+)
+
+// Min checks that an integer is at least n.
+func Min(n int64) Rule {
+	return NewRule("min", func(v any) string {
+		i, ok := integer(v)
+		if !ok {
+			return "must be an integer"
+		}
+		if i < n {
+			return fmt.Sprintf("must be at least %d", n)
+		}
+		return ""
+	})
+}
+
+// Range checks that an integer lies within [lo, hi].
+func Range(lo, hi int64) Rule {
+	return rangeRule("range", lo, hi)
+}
+
+func rangeRule(name string, lo, hi int64) Rule {
+	return NewRule(name, func(v any) string {
+		i, ok := integer(v)
+		if !ok {
+			return "must be an integer"
+		}
+		if i < lo || i > hi {
+			return fmt.Sprintf("must be between %d and %d", lo, hi)
+		}
+		return ""
+	})
+}
+
+func stringRule(name, msg string, valid func(string) bool) Rule {
+	return NewRule(name, func(v any) string {
+		s, ok := v.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if !valid(s) {
+			return msg
+		}
+		return ""
+	})
+}
+
+func integer(v any) (int64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return i, err == nil
+}
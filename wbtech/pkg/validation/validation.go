@@ -0,0 +1,135 @@
+// Package validation checks JSON documents against a schema of reusable rules
+// and reports every violation with its JSON path. It works on the raw document
+// rather than a decoded struct, so a missing field is told apart from a
+// legitimate zero value.
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Violation struct {
+	Path    string `json:"path"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors is returned when a document breaks at least one rule.
+type Errors []Violation
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, v := range e {
+		parts[i] = fmt.Sprintf("%s: %s (%s)", v.Path, v.Message, v.Rule)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Schema describes the expected shape of a JSON value.
+type Schema interface {
+	validate(path string, v any, present bool, errs *Errors)
+}
+
+// Object checks the listed fields of a JSON object; other fields are ignored.
+type Object map[string]Schema
+
+func (o Object) validate(path string, v any, present bool, errs *Errors) {
+	if !present || v == nil {
+		errs.add(path, Required.Name, "is required")
+		return
+	}
+	fields, ok := v.(map[string]any)
+	if !ok {
+		errs.add(path, "object", "must be an object")
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(o)) {
+		value, present := fields[name]
+		o[name].validate(path+"."+name, value, present, errs)
+	}
+}
+
+// Array checks a JSON array with at least Min elements, each against Items.
+type Array struct {
+	Min   int
+	Items Schema
+}
+
+func (a Array) validate(path string, v any, present bool, errs *Errors) {
+	if !present || v == nil {
+		errs.add(path, Required.Name, "is required")
+		return
+	}
+	items, ok := v.([]any)
+	if !ok {
+		errs.add(path, "array", "must be an array")
+		return
+	}
+	if len(items) < a.Min {
+		errs.add(path, "min_items", fmt.Sprintf("must have at least %d items", a.Min))
+		return
+	}
+
+	for i, item := range items {
+		a.Items.validate(path+"["+strconv.Itoa(i)+"]", item, true, errs)
+	}
+}
+
+type value struct {
+	rules []Rule
+}
+
+// Value checks a scalar against rules in order and reports only the first one
+// it breaks. A missing or null value is a violation only with Required.
+func Value(rules ...Rule) Schema {
+	return value{rules: rules}
+}
+
+func (s value) validate(path string, v any, present bool, errs *Errors) {
+	if !present || v == nil {
+		if slices.ContainsFunc(s.rules, func(r Rule) bool { return r.Name == Required.Name }) {
+			errs.add(path, Required.Name, "is required")
+		}
+		return
+	}
+
+	for _, rule := range s.rules {
+		if rule.check == nil {
+			continue
+		}
+		if msg := rule.check(v); msg != "" {
+			errs.add(path, rule.Name, msg)
+			return
+		}
+	}
+}
+
+// Validate decodes data and checks it against schema. It returns Errors when
+// the document is valid JSON but breaks a rule.
+func Validate(data []byte, schema Schema) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	var errs Errors
+	schema.validate("$", doc, true, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (e *Errors) add(path, rule, msg string) {
+	*e = append(*e, Violation{Path: path, Rule: rule, Message: msg})
+}
@@ -0,0 +1,302 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+const validOrder = `{
+	"order_uid": "b563feb7b2b84b6test", "track_number": "WBILMTESTTRACK", "entry": "WBIL", "locale": "en",
+	"internal_signature": "", "customer_id": "test", "delivery_service": "meest", "shardkey": "9", "sm_id": 99,
+	"date_created": "2021-11-26T06:22:19Z", "oof_shard": "1",
+	"delivery": {"name": "Test Testov", "phone": "+9720000000", "zip": "2639809", "city": "Kiryat Mozkin",
+		"address": "Ploshad Mira 15", "region": "Kraiot", "email": "test@gmail.com"},
+	"payment": {"transaction": "b563feb7b2b84b6test", "request_id": "", "currency": "USD", "provider": "wbpay",
+		"amount": 1817, "payment_dt": 1637907727, "bank": "alpha", "delivery_cost": 1500, "goods_total": 317, "custom_fee": 0},
+	"items": [{"chrt_id": 9934930, "track_number": "WBILMTESTTRACK", "price": 453, "rid": "ab4219087a764ae0btest",
+		"name": "Mascaras", "sale": 30, "size": "0", "total_price": 317, "nm_id": 2389212, "brand": "Vivienne Sabo", "status": 202}]
+}`
+
+// decodeValue decodes a JSON literal the way Validate does.
+func decodeValue(t *testing.T, literal string) any {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(literal)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode %s: %v", literal, err)
+	}
+	return v
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule  Rule
+		value string
+		ok    bool
+	}{
+		{String, `"x"`, true},
+		{String, `""`, true},
+		{String, `1`, false},
+
+		{NotBlank, `"x"`, true},
+		{NotBlank, `"  "`, false},
+		{NotBlank, `""`, false},
+		{NotBlank, `1`, false},
+
+		{Email, `"test@gmail.com"`, true},
+		{Email, `"test@localhost"`, true},
+		{Email, `"test"`, false},
+		{Email, `"Test <test@gmail.com>"`, false},
+		{Email, `"@gmail.com"`, false},
+
+		{Phone, `"+9720000000"`, true},
+		{Phone, `"+7 (912) 345-67-89"`, true},
+		{Phone, `"1234567"`, true},
+		{Phone, `"123456"`, false},
+		{Phone, `"1234567890123456"`, false},
+		{Phone, `"+7 912 abc"`, false},
+
+		{Zip, `"2639809"`, true},
+		{Zip, `"SW1A 1AA"`, true},
+		{Zip, `"10115"`, true},
+		{Zip, `"1"`, false},
+		{Zip, `"-2639809"`, false},
+		{Zip, `"12345678901"`, false},
+
+		{Currency, `"USD"`, true},
+		{Currency, `"RUB"`, true},
+		{Currency, `"usd"`, false},
+		{Currency, `"XXX"`, false},
+		{Currency, `"US"`, false},
+
+		{Locale, `"en"`, true},
+		{Locale, `"ru-RU"`, true},
+		{Locale, `"es_419"`, true},
+		{Locale, `"EN"`, false},
+		{Locale, `"en-us"`, false},
+		{Locale, `"english"`, false},
+
+		{RFC3339, `"2021-11-26T06:22:19Z"`, true},
+		{RFC3339, `"2021-11-26T06:22:19+03:00"`, true},
+		{RFC3339, `"2021-11-26"`, false},
+		{RFC3339, `"2021-11-26 06:22:19"`, false},
+
+		{Integer, `0`, true},
+		{Integer, `-5`, true},
+		{Integer, `1.5`, false},
+		{Integer, `"1"`, false},
+		{Integer, `9223372036854775808`, false},
+
+		{Int32, `2147483647`, true},
+		{Int32, `-2147483648`, true},
+		{Int32, `2147483648`, false},
+
+		{Min(0), `0`, true},
+		{Min(0), `-1`, false},
+		{Min(1), `0`, false},
+		{Min(1), `"1"`, false},
+
+		{Range(0, 100), `0`, true},
+		{Range(0, 100), `100`, true},
+		{Range(0, 100), `101`, false},
+		{Range(0, 100), `-1`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule.Name+" "+tt.value, func(t *testing.T) {
+			msg := tt.rule.check(decodeValue(t, tt.value))
+			if (msg == "") != tt.ok {
+				t.Errorf("check = %q, want ok %v", msg, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(order map[string]any)
+		want   []Violation
+	}{
+		{
+			name:   "valid order",
+			mutate: func(map[string]any) {},
+		},
+		{
+			name: "zero amounts and sale are valid",
+			mutate: func(o map[string]any) {
+				o["payment"].(map[string]any)["amount"] = 0
+				o["payment"].(map[string]any)["goods_total"] = 0
+				item(o, 0)["sale"] = 0
+				item(o, 0)["price"] = 0
+			},
+		},
+		{
+			name: "optional fields may be missing or null",
+			mutate: func(o map[string]any) {
+				delete(o, "internal_signature")
+				o["payment"].(map[string]any)["request_id"] = nil
+				delete(o["payment"].(map[string]any), "custom_fee")
+				delete(item(o, 0), "sale")
+			},
+		},
+		{
+			name: "missing and null required fields",
+			mutate: func(o map[string]any) {
+				delete(o, "order_uid")
+				o["track_number"] = nil
+				delete(o["delivery"].(map[string]any), "email")
+			},
+			want: []Violation{
+				{Path: "$.delivery.email", Rule: "required"},
+				{Path: "$.order_uid", Rule: "required"},
+				{Path: "$.track_number", Rule: "required"},
+			},
+		},
+		{
+			name: "missing nested object",
+			mutate: func(o map[string]any) {
+				delete(o, "payment")
+			},
+			want: []Violation{{Path: "$.payment", Rule: "required"}},
+		},
+		{
+			name: "wrong types",
+			mutate: func(o map[string]any) {
+				o["delivery"] = "Kiryat Mozkin"
+				o["items"] = map[string]any{}
+				o["sm_id"] = "99"
+			},
+			want: []Violation{
+				{Path: "$.delivery", Rule: "object"},
+				{Path: "$.items", Rule: "array"},
+				{Path: "$.sm_id", Rule: "integer"},
+			},
+		},
+		{
+			name: "formats",
+			mutate: func(o map[string]any) {
+				o["locale"] = "english"
+				o["date_created"] = "26.11.2021"
+				o["delivery"].(map[string]any)["phone"] = "call me"
+				o["delivery"].(map[string]any)["zip"] = "?"
+				o["delivery"].(map[string]any)["email"] = "test"
+				o["payment"].(map[string]any)["currency"] = "dollars"
+			},
+			want: []Violation{
+				{Path: "$.date_created", Rule: "rfc3339"},
+				{Path: "$.delivery.email", Rule: "email"},
+				{Path: "$.delivery.phone", Rule: "phone"},
+				{Path: "$.delivery.zip", Rule: "zip"},
+				{Path: "$.locale", Rule: "locale"},
+				{Path: "$.payment.currency", Rule: "currency"},
+			},
+		},
+		{
+			name: "blank text",
+			mutate: func(o map[string]any) {
+				o["customer_id"] = " "
+			},
+			want: []Violation{{Path: "$.customer_id", Rule: "not_blank"}},
+		},
+		{
+			name: "numeric limits report the first broken rule only",
+			mutate: func(o map[string]any) {
+				o["sm_id"] = 0
+				o["payment"].(map[string]any)["amount"] = -1
+				o["payment"].(map[string]any)["delivery_cost"] = 1.5
+				o["payment"].(map[string]any)["payment_dt"] = 0
+			},
+			want: []Violation{
+				{Path: "$.payment.amount", Rule: "min"},
+				{Path: "$.payment.delivery_cost", Rule: "integer"},
+				{Path: "$.payment.payment_dt", Rule: "min"},
+				{Path: "$.sm_id", Rule: "min"},
+			},
+		},
+		{
+			name: "amount beyond an integer column",
+			mutate: func(o map[string]any) {
+				o["payment"].(map[string]any)["amount"] = 1 << 31
+			},
+			want: []Violation{{Path: "$.payment.amount", Rule: "int32"}},
+		},
+		{
+			name: "no items",
+			mutate: func(o map[string]any) {
+				o["items"] = []any{}
+			},
+			want: []Violation{{Path: "$.items", Rule: "min_items"}},
+		},
+		{
+			name: "item paths carry the index",
+			mutate: func(o map[string]any) {
+				second := map[string]any{}
+				for k, v := range item(o, 0) {
+					second[k] = v
+				}
+				second["sale"] = 101
+				delete(second, "rid")
+				o["items"] = append(o["items"].([]any), second)
+			},
+			want: []Violation{
+				{Path: "$.items[1].rid", Rule: "required"},
+				{Path: "$.items[1].sale", Rule: "range"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var order map[string]any
+			if err := json.Unmarshal([]byte(validOrder), &order); err != nil {
+				t.Fatal(err)
+			}
+			tt.mutate(order)
+			data, err := json.Marshal(order)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = ValidateOrder(data)
+			var got Errors
+			if err != nil && !errors.As(err, &got) {
+				t.Fatalf("err = %v, want validation.Errors", err)
+			}
+			if !slices.EqualFunc(got, tt.want, func(g, w Violation) bool {
+				return g.Path == w.Path && g.Rule == w.Rule && g.Message != ""
+			}) {
+				t.Errorf("violations = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func item(order map[string]any, i int) map[string]any {
+	return order["items"].([]any)[i].(map[string]any)
+}
+
+func TestValidateInvalidJSON(t *testing.T) {
+	for _, data := range []string{``, `{"order_uid":`, `not json`} {
+		err := ValidateOrder([]byte(data))
+		if err == nil || errors.As(err, new(Errors)) {
+			t.Errorf("ValidateOrder(%q) = %v, want a decode error", data, err)
+		}
+	}
+}
+
+func TestErrorsMessage(t *testing.T) {
+	err := Errors{
+		{Path: "$.order_uid", Rule: "required", Message: "is required"},
+		{Path: "$.payment.currency", Rule: "currency", Message: "must be an ISO 4217 currency code"},
+	}
+	want := "$.order_uid: is required (required); $.payment.currency: must be an ISO 4217 currency code (currency)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}